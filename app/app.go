@@ -0,0 +1,168 @@
+// Package app wires every component of ddp-sender together and manages their lifecycle.
+package app
+
+import (
+	"context"
+	"ddp-sender/config"
+	"ddp-sender/layout"
+	"ddp-sender/led"
+	"ddp-sender/listener"
+	"ddp-sender/osc"
+	"ddp-sender/output"
+	"ddp-sender/scheduler"
+	"ddp-sender/updater"
+	"ddp-sender/webserver"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+type App struct {
+	cfg          *config.Config
+	outputs      *output.Manager
+	ledArray     *led.LEDArrayColor
+	midiReceiver *listener.UDPMidiReceiver
+	midiStream   *listener.TCPMidiReceiver // Nil when midi_tcp_port is 0.
+	rtpMidi      *listener.RTPMidiReceiver // Nil when rtp_midi_port is 0.
+	oscServer    *osc.Server               // Nil when osc_port is 0.
+	updater      *updater.Updater
+	scheduler    *scheduler.Scheduler
+	webServer    *webserver.WebServer
+}
+
+func New(cfg *config.Config) (*App, error) {
+	outputs, err := output.NewManager(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pixelLayout, err := layout.New(cfg.Layout, cfg.LEDAmount)
+	if err != nil {
+		outputs.Close()
+		return nil, err
+	}
+
+	midiReceiver := listener.NewUDPMidiReceiver(cfg.MidiPort)
+	midiReceiver.Raw = cfg.MidiFormat == config.MIDI_FORMAT_RAW
+	var midiStream *listener.TCPMidiReceiver
+	if cfg.MidiTCPPort != 0 {
+		midiStream = listener.NewTCPMidiReceiver(cfg.MidiTCPPort, midiReceiver.SendChannel)
+	}
+	var rtpMidi *listener.RTPMidiReceiver
+	if cfg.RTPMidiPort != 0 {
+		rtpMidi = listener.NewRTPMidiReceiver(cfg.RTPMidiPort, cfg.RTPMidiName, midiReceiver.SendChannel)
+	}
+	ledArray := led.NewLEDArrayColor(cfg.LEDAmount)
+	updater := updater.NewUpdater(cfg, pixelLayout, ledArray, midiReceiver.SendChannel)
+	updater.SetMasterControl(ledArray)
+	var oscServer *osc.Server
+	if cfg.OSCPort != 0 {
+		oscServer = osc.NewServer(cfg.OSCPort, updater.HandleOSC)
+	}
+
+	return &App{
+		cfg:          cfg,
+		outputs:      outputs,
+		ledArray:     ledArray,
+		midiReceiver: midiReceiver,
+		midiStream:   midiStream,
+		rtpMidi:      rtpMidi,
+		oscServer:    oscServer,
+		updater:      updater,
+		scheduler:    scheduler.NewScheduler(ledArray, outputs, cfg.RefreshRate.Duration()),
+		webServer:    webserver.NewWebServer(cfg, ledArray, outputs, updater),
+	}, nil
+}
+
+// Run starts every component and blocks until ctx is cancelled or the MIDI listener fails.
+// On return all goroutines have stopped and the outputs are closed.
+func (a *App) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	run := func(name string, component func(ctx context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Printf("Launched %s\n", name)
+			if err := component(ctx); err != nil {
+				log.Printf("%s error: %v\n", name, err)
+			}
+			log.Printf("Stopped %s\n", name)
+		}()
+	}
+
+	var listenerErr error
+	run("MidiListener", func(ctx context.Context) error {
+		listenerErr = a.midiReceiver.RunListener(ctx)
+		// Without MIDI input there is nothing to show, stop everything else.
+		cancel()
+		return listenerErr
+	})
+	if a.midiStream != nil {
+		run("MidiStreamListener", a.midiStream.RunListener)
+	}
+	if a.rtpMidi != nil {
+		run("RTPMidiListener", a.rtpMidi.RunListener)
+	}
+	if a.oscServer != nil {
+		run("OSCListener", a.oscServer.RunListener)
+	}
+	run("LedUpdater", func(ctx context.Context) error {
+		a.updater.Run(ctx)
+		return nil
+	})
+	run("FrameScheduler", func(ctx context.Context) error {
+		a.scheduler.Run(ctx)
+		return nil
+	})
+	// Setup custom mapper listener (to receive custom presets from REAPER)
+	run("MappingListener", a.updater.GetCustomMapper().RunListener)
+	run("WebServer", a.webServer.Start)
+	run("Monitor", a.monitor)
+
+	<-ctx.Done()
+	wg.Wait()
+
+	if a.cfg.BlackoutOnExit {
+		a.outputs.Send(make([]colorful.Color, a.cfg.LEDAmount))
+	}
+	if err := a.outputs.Close(); err != nil {
+		log.Printf("Output close error: %v\n", err)
+	}
+	return listenerErr
+}
+
+// monitor logs the frame clock counters and the writes/s of every output.
+func (a *App) monitor(ctx context.Context) error {
+	interval := a.cfg.MonitorInterval.Duration()
+	seconds := int64(interval / time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previousFrames scheduler.Stats
+	previous := make(map[string]output.Stats)
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+		frames := a.scheduler.Stats()
+		log.Printf("Frames - %d/s (avg %s), %d late, %d skipped, last render %s\n",
+			(frames.Frames-previousFrames.Frames)/seconds, interval,
+			frames.Late-previousFrames.Late, frames.Skipped-previousFrames.Skipped, frames.LastRender)
+		previousFrames = frames
+		for _, stats := range a.outputs.Stats() {
+			last := previous[stats.Name]
+			log.Printf("Output %s - %d updates/s (avg %s), %d errors, %d dropped, last write %s, %.0fmA (scale %.2f)\n",
+				stats.Name, (stats.Sent-last.Sent)/seconds, interval,
+				stats.Errors-last.Errors, stats.Dropped-last.Dropped, stats.LastLatency,
+				stats.Power.LimitedMilliamps, stats.Power.Scale)
+			previous[stats.Name] = stats
+		}
+	}
+}
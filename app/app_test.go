@@ -0,0 +1,94 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"ddp-sender/app"
+	"ddp-sender/config"
+	"net"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestApp_RunAndShutdown(t *testing.T) {
+	// Fake DDP controller
+	controller, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP: %v", err)
+	}
+	defer controller.Close()
+
+	cfg := config.Default()
+	cfg.DDPEndpoint = controller.LocalAddr().String()
+	cfg.MappingsDir = "../mappings"
+	cfg.MidiPort = freePort(t)
+	cfg.WebUIPort = freePort(t)
+	cfg.ReaperPort = freePort(t)
+
+	application, err := app.New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- application.Run(ctx)
+	}()
+
+	// The startup effects light LEDs, wait for a lit frame.
+	buf := make([]byte, 2048)
+	readFrame := func() []byte {
+		controller.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := controller.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("Failed to read DDP packet: %v", err)
+		}
+		return buf[10:n]
+	}
+	blackout := make([]byte, 3*cfg.LEDAmount)
+	for bytes.Equal(readFrame(), blackout) {
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after cancellation")
+	}
+
+	// The last frame sent is the blackout frame.
+	var last []byte
+	for {
+		controller.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := controller.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		last = append(last[:0], buf[10:n]...)
+	}
+	if !bytes.Equal(last, blackout) {
+		t.Errorf("Last frame is not a blackout frame")
+	}
+
+	// Every port is released.
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: cfg.MidiPort})
+	if err != nil {
+		t.Errorf("MIDI port still in use: %v", err)
+	} else {
+		conn.Close()
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	CALIBRATION_NONE   = "none"   // No correction.
+	CALIBRATION_LEGACY = "legacy" // Green/blue reduction of the original WS2812 strip.
+)
+
+// CalibrationProfile describes how colors are corrected before reaching an output.
+// Channels go through gamma, gain, white point and finally the lookup table.
+type CalibrationProfile struct {
+	Gain       *RGBValues `json:"gain,omitempty"`        // Multiplier per channel, defaults to 1.
+	Gamma      *RGBValues `json:"gamma,omitempty"`       // Exponent per channel, defaults to 1 (linear).
+	WhitePoint string     `json:"white_point,omitempty"` // Hex color full white is mapped to, defaults to #ffffff.
+	LUT        *RGBTables `json:"lut,omitempty"`         // 256 entry output table per channel.
+}
+
+type RGBValues struct {
+	R float64 `json:"r"`
+	G float64 `json:"g"`
+	B float64 `json:"b"`
+}
+
+type RGBTables struct {
+	R []int `json:"r"`
+	G []int `json:"g"`
+	B []int `json:"b"`
+}
+
+// BuiltinCalibrations returns the profiles that are always available.
+func BuiltinCalibrations() map[string]CalibrationProfile {
+	return map[string]CalibrationProfile{
+		CALIBRATION_NONE:   {},
+		CALIBRATION_LEGACY: {LUT: legacyLUT()},
+	}
+}
+
+// legacyLUT reproduces the piecewise correction hardcoded for the first strip.
+func legacyLUT() *RGBTables {
+	tables := &RGBTables{R: make([]int, 256), G: make([]int, 256), B: make([]int, 256)}
+	for i := range 256 {
+		value := float64(i)
+		tables.R[i] = i
+		switch {
+		case i > 15:
+			tables.G[i], tables.B[i] = int(value*0.43), int(value*0.3)
+		case i > 5:
+			tables.G[i], tables.B[i] = int(value*0.6), int(value*0.45)
+		default:
+			tables.G[i], tables.B[i] = int(value*0.75), int(value*0.53)
+		}
+	}
+	return tables
+}
+
+// Calibration returns the named profile, looking at the configured profiles first.
+func (c *Config) Calibration(name string) (CalibrationProfile, bool) {
+	if profile, ok := c.Calibrations[name]; ok {
+		return profile, true
+	}
+	profile, ok := BuiltinCalibrations()[name]
+	return profile, ok
+}
+
+func (p CalibrationProfile) validate(name string) []string {
+	var problems []string
+	field := fmt.Sprintf("calibrations[%s]", name)
+	for _, check := range []struct {
+		name   string
+		values *RGBValues
+	}{{"gain", p.Gain}, {"gamma", p.Gamma}} {
+		if check.values == nil {
+			continue
+		}
+		for _, value := range []float64{check.values.R, check.values.G, check.values.B} {
+			if value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+				problems = append(problems, fmt.Sprintf("%s %s values must be positive (got %+v)", field, check.name, *check.values))
+				break
+			}
+		}
+	}
+	if p.WhitePoint != "" {
+		if _, err := colorful.Hex(p.WhitePoint); err != nil {
+			problems = append(problems, fmt.Sprintf("%s white_point %q is not a hex color", field, p.WhitePoint))
+		}
+	}
+	if p.LUT != nil {
+		for i, table := range [][]int{p.LUT.R, p.LUT.G, p.LUT.B} {
+			channel := "rgb"[i : i+1]
+			if len(table) != 256 {
+				problems = append(problems, fmt.Sprintf("%s lut.%s must have 256 entries (got %d)", field, channel, len(table)))
+				continue
+			}
+			for _, value := range table {
+				if value < 0 || value > 255 {
+					problems = append(problems, fmt.Sprintf("%s lut.%s values must be between 0 and 255", field, channel))
+					break
+				}
+			}
+		}
+	}
+	return problems
+}
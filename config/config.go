@@ -1,14 +1,247 @@
+// Package config loads the runtime configuration of ddp-sender.
+//
+// Values are resolved with the following precedence (highest wins):
+//
+//  1. Command-line flags (e.g. -led-amount 300)
+//  2. Environment variables (e.g. DDP_SENDER_LED_AMOUNT=300)
+//  3. The JSON config file given by -config or DDP_SENDER_CONFIG
+//  4. Built-in defaults (see Default)
 package config
 
-import "time"
+import (
+	"ddp-sender/layout"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
 
-const LED_AMOUNT int = 150
-const DDP_ENDPOINT string = "192.168.0.30:4048"
-const MONITOR_TICKER_INTERVAL int = 1
-const LED_REFRESH_RATE = 20 * time.Millisecond
-const MAPPINGS_DIR = "./mappings"
+const (
+	MIDI_FORMAT_PACKET = "packet" // Legacy 4 byte or versioned packets, see listener.DecodePacket.
+	MIDI_FORMAT_RAW    = "raw"    // MIDI 1.0 byte stream, see listener.Parser.
+)
 
-var CURRENT_MAPPING = "uprising.json"
+type Config struct {
+	LEDAmount       int      `json:"led_amount"`
+	DDPEndpoint     string   `json:"ddp_endpoint"`
+	RefreshRate     Duration `json:"refresh_rate"`
+	MonitorInterval Duration `json:"monitor_interval"`
+	MappingsDir     string   `json:"mappings_dir"`
+	DefaultMapping  string   `json:"default_mapping"`
+	DrumsDir        string   `json:"drums_dir"`
+	DrumKit         string   `json:"drum_kit"` // Drum kit profile of the drums channel, in DrumsDir.
+	WebUIPort       int      `json:"web_ui_port"`
+	WebUIDir        string   `json:"web_ui_dir"`
+	MidiPort        int      `json:"midi_port"`
+	MidiFormat      string   `json:"midi_format"`   // UDP datagrams are packets (legacy/versioned) or raw MIDI bytes.
+	MidiTCPPort     int      `json:"midi_tcp_port"` // Raw MIDI byte stream listener, 0 disables it.
+	RTPMidiPort     int      `json:"rtp_midi_port"` // AppleMIDI control port (data on the next one), 0 disables it.
+	RTPMidiName     string   `json:"rtp_midi_name"` // Session name shown by the DAW.
+	OSCPort         int      `json:"osc_port"`      // OSC over UDP listener, 0 disables it.
+	ReaperPort      int      `json:"reaper_port"`
+	BlackoutOnExit  bool     `json:"blackout_on_exit"`
 
-const WEB_UI_PORT = 8081
-const WEB_UI_DIR = "./webserver/ui/dist"
+	// Outputs lists the controllers the canvas is sent to. When empty a single
+	// output covering the whole canvas is sent to DDPEndpoint.
+	Outputs []OutputConfig `json:"outputs,omitempty"`
+
+	// Master maps MIDI notes to the master brightness, blackout and freeze controls.
+	Master MasterConfig `json:"master"`
+
+	// Calibrations adds named color calibration profiles to the built-in
+	// "none" and "legacy" ones, outputs pick one by name.
+	Calibrations map[string]CalibrationProfile `json:"calibrations,omitempty"`
+
+	// Routes sends every MIDI channel, or notes of a channel, to its handlers. Messages of
+	// no route are dropped, the master channel is handled before the routes.
+	Routes []RouteConfig `json:"routes"`
+
+	// Transition hands the effects over when switching mappings without an explicit transition,
+	// by MIDI program change in particular.
+	Transition TransitionConfig `json:"transition"`
+
+	// Programs lists the mapping files selected by MIDI program change 0, 1... on the custom mapping channel.
+	Programs []string `json:"programs,omitempty"`
+
+	// Layout places the LEDs in space so presets can target zones and rectangles.
+	Layout layout.Config `json:"layout"`
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() *Config {
+	return &Config{
+		LEDAmount:       150,
+		DDPEndpoint:     "192.168.0.30:4048",
+		RefreshRate:     Duration(20 * time.Millisecond),
+		MonitorInterval: Duration(1 * time.Second),
+		MappingsDir:     "./mappings",
+		DefaultMapping:  "uprising.json",
+		DrumsDir:        "./drums",
+		DrumKit:         "default.json",
+		WebUIPort:       8081,
+		WebUIDir:        "./webserver/ui/dist",
+		MidiPort:        8090,
+		MidiFormat:      MIDI_FORMAT_PACKET,
+		RTPMidiName:     "ddp-sender",
+		ReaperPort:      8080,
+		BlackoutOnExit:  true,
+		Master:          defaultMaster(),
+		Routes:          defaultRoutes(),
+		Transition:      defaultTransition(),
+	}
+}
+
+// Validate checks every field and returns a ValidationError listing all the problems found.
+func (c *Config) Validate() error {
+	var problems []string
+	if c.LEDAmount <= 0 {
+		problems = append(problems, fmt.Sprintf("led_amount must be positive (got %d)", c.LEDAmount))
+	}
+	if len(c.Outputs) == 0 {
+		if err := validateHostPort(c.DDPEndpoint); err != nil {
+			problems = append(problems, fmt.Sprintf("ddp_endpoint %v", err))
+		}
+	}
+	if c.RefreshRate.Duration() <= 0 {
+		problems = append(problems, fmt.Sprintf("refresh_rate must be positive (got %s)", c.RefreshRate))
+	}
+	if c.MonitorInterval.Duration() < time.Second {
+		problems = append(problems, fmt.Sprintf("monitor_interval must be at least 1s (got %s)", c.MonitorInterval))
+	}
+	if c.MappingsDir == "" {
+		problems = append(problems, "mappings_dir must not be empty")
+	}
+	if c.DefaultMapping == "" {
+		problems = append(problems, "default_mapping must not be empty")
+	}
+	if c.DrumsDir == "" {
+		problems = append(problems, "drums_dir must not be empty")
+	}
+	if c.DrumKit == "" {
+		problems = append(problems, "drum_kit must not be empty")
+	}
+	problems = append(problems, validatePort("web_ui_port", c.WebUIPort)...)
+	problems = append(problems, validatePort("midi_port", c.MidiPort)...)
+	problems = append(problems, validatePort("midi_tcp_port", c.MidiTCPPort)...)
+	if c.RTPMidiPort == 65535 {
+		problems = append(problems, "rtp_midi_port must leave room for the data port (got 65535)")
+	}
+	problems = append(problems, validatePort("rtp_midi_port", c.RTPMidiPort)...)
+	if c.RTPMidiPort != 0 && c.RTPMidiName == "" {
+		problems = append(problems, "rtp_midi_name must not be empty")
+	}
+	if c.MidiFormat != MIDI_FORMAT_PACKET && c.MidiFormat != MIDI_FORMAT_RAW {
+		problems = append(problems, fmt.Sprintf("midi_format must be %s or %s (got %q)", MIDI_FORMAT_PACKET, MIDI_FORMAT_RAW, c.MidiFormat))
+	}
+	problems = append(problems, validatePort("osc_port", c.OSCPort)...)
+	problems = append(problems, validatePort("reaper_port", c.ReaperPort)...)
+	if c.WebUIPort != 0 && c.WebUIPort == c.ReaperPort {
+		problems = append(problems, fmt.Sprintf("web_ui_port and reaper_port must differ (both %d)", c.WebUIPort))
+	}
+	problems = append(problems, c.Master.validate()...)
+	for i, route := range c.Routes {
+		problems = append(problems, route.validate(i)...)
+	}
+	if err := c.Transition.Validate(); err != nil {
+		problems = append(problems, fmt.Sprintf("transition.%v", err))
+	}
+	if len(c.Programs) > 128 {
+		problems = append(problems, fmt.Sprintf("programs can list up to 128 mappings (got %d)", len(c.Programs)))
+	}
+	for i, program := range c.Programs {
+		if program == "" {
+			problems = append(problems, fmt.Sprintf("programs[%d] must not be empty", i))
+		}
+	}
+	names := make([]string, 0, len(c.Calibrations))
+	for name := range c.Calibrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		problems = append(problems, c.Calibrations[name].validate(name)...)
+	}
+	if len(c.Outputs) > 0 {
+		problems = append(problems, c.validateOutputs()...)
+	}
+	if c.LEDAmount > 0 {
+		problems = append(problems, c.Layout.Validate(c.LEDAmount)...)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validatePort(name string, port int) []string {
+	if port < 0 || port > 65535 {
+		return []string{fmt.Sprintf("%s must be between 0 and 65535 (got %d)", name, port)}
+	}
+	return nil
+}
+
+func validateHostPort(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("must be host:port (got %q)", address)
+	}
+	if host == "" {
+		return fmt.Errorf("is missing a host (got %q)", address)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return fmt.Errorf("has an invalid port (got %q)", address)
+	}
+	return nil
+}
+
+// validateHost accepts a host with or without a port, the driver has a default port.
+func validateHost(address string) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		if address == "" {
+			return fmt.Errorf("is missing a host (got %q)", address)
+		}
+		return nil
+	}
+	return validateHostPort(address)
+}
+
+// ValidationError lists every invalid field of a Config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Duration is a time.Duration that is written as a string ("20ms") in config files.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"20ms\": %w", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
@@ -0,0 +1,153 @@
+package config_test
+
+import (
+	"ddp-sender/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := []byte(`{"led_amount": 300, "ddp_endpoint": "10.0.0.5:4048", "refresh_rate": "25ms", "midi_port": 9000}`)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("DDP_SENDER_CONFIG", path)
+	t.Setenv("DDP_SENDER_LED_AMOUNT", "400")
+	t.Setenv("DDP_SENDER_MIDI_PORT", "9100")
+
+	cfg, err := config.Load([]string{"-midi-port", "9200"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.DDPEndpoint != "10.0.0.5:4048" {
+		t.Errorf("DDPEndpoint = %s, want value from file", cfg.DDPEndpoint)
+	}
+	if cfg.RefreshRate.Duration() != 25*time.Millisecond {
+		t.Errorf("RefreshRate = %s, want 25ms", cfg.RefreshRate)
+	}
+	if cfg.LEDAmount != 400 {
+		t.Errorf("LEDAmount = %d, want environment value 400", cfg.LEDAmount)
+	}
+	if cfg.MidiPort != 9200 {
+		t.Errorf("MidiPort = %d, want flag value 9200", cfg.MidiPort)
+	}
+	if cfg.WebUIPort != config.Default().WebUIPort {
+		t.Errorf("WebUIPort = %d, want default %d", cfg.WebUIPort, config.Default().WebUIPort)
+	}
+}
+
+func TestLoad_ReportsEveryInvalidField(t *testing.T) {
+	_, err := config.Load([]string{
+		"-led-amount", "0",
+		"-ddp-endpoint", "nowhere",
+		"-refresh-rate", "fast",
+		"-web-ui-port", "70000",
+	})
+
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load() error = %v, want *ValidationError", err)
+	}
+	if len(validationErr.Problems) != 4 {
+		t.Errorf("Problems = %q, want 4 entries", validationErr.Problems)
+	}
+}
+
+func TestValidate_Routes(t *testing.T) {
+	cfg := config.Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() with the default routes error = %v", err)
+	}
+
+	cfg.Routes = []config.RouteConfig{
+		{Channel: 17, Handlers: []config.HandlerConfig{{Type: config.HANDLER_DIRECT}}},
+		{Channel: 4, Notes: &config.NoteRange{Low: 60, High: 40}, Handlers: []config.HandlerConfig{{Type: config.HANDLER_MAPPING, File: "a.json"}}},
+		{Channel: 5},
+		{Channel: 6, Handlers: []config.HandlerConfig{{Type: "lasers"}, {Type: config.HANDLER_DIRECT, File: "a.json"}}},
+	}
+	var validationErr *config.ValidationError
+	if err := cfg.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+	if len(validationErr.Problems) != 5 {
+		t.Errorf("Problems = %q, want 5 entries", validationErr.Problems)
+	}
+}
+
+func TestResolvedOutputs_SACN(t *testing.T) {
+	cfg := config.Default()
+	zero := 0
+	cfg.Outputs = []config.OutputConfig{
+		{Name: "default", Protocol: config.PROTOCOL_SACN, Address: "10.0.0.1:5568"},
+		{Name: "background", Protocol: config.PROTOCOL_SACN, Address: "10.0.0.2:5568", SACN: &config.SACNConfig{Universe: 1, Priority: &zero}},
+	}
+	outputs := cfg.ResolvedOutputs()
+	if got := *outputs[0].SACN.Priority; got != 100 {
+		t.Errorf("Default priority = %d, want 100", got)
+	}
+	if got := *outputs[1].SACN.Priority; got != 0 {
+		t.Errorf("Configured priority = %d, want 0", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	// The last universe of the segment is out of range.
+	cfg.LEDAmount = 200
+	cfg.Outputs = []config.OutputConfig{{Protocol: config.PROTOCOL_SACN, Address: "10.0.0.1:5568", SACN: &config.SACNConfig{Universe: 63999}}}
+	var validationErr *config.ValidationError
+	if err := cfg.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+	if len(validationErr.Problems) != 1 {
+		t.Errorf("Problems = %q, want 1 entry", validationErr.Problems)
+	}
+}
+
+func TestValidate_ArtNetUniverses(t *testing.T) {
+	cfg := config.Default()
+	cfg.LEDAmount = 200
+	cfg.Outputs = []config.OutputConfig{{Protocol: config.PROTOCOL_ARTNET, Address: "10.0.0.1:6454", ArtNet: &config.ArtNetConfig{Net: 127, Subnet: 15, Universe: 14}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with the last two Port-Addresses error = %v", err)
+	}
+
+	// 200 LEDs span two universes, the second one past Port-Address 32767.
+	cfg.Outputs[0].ArtNet.Universe = 15
+	var validationErr *config.ValidationError
+	if err := cfg.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+	if len(validationErr.Problems) != 1 {
+		t.Errorf("Problems = %q, want 1 entry", validationErr.Problems)
+	}
+}
+
+func TestValidate_WLEDAddress(t *testing.T) {
+	for address, valid := range map[string]bool{"10.0.0.5": true, "wled.local:21324": true, "10.0.0.5:": false, "": false} {
+		cfg := config.Default()
+		cfg.Outputs = []config.OutputConfig{{Protocol: config.PROTOCOL_WLED, Address: address}}
+		if err := cfg.Validate(); (err == nil) != valid {
+			t.Errorf("Validate() with address %q error = %v, want valid %t", address, err, valid)
+		}
+	}
+}
+
+func TestValidate_Transition(t *testing.T) {
+	cfg := config.Default()
+	cfg.Transition = config.TransitionConfig{Type: "dissolve", Duration: config.Duration(-time.Second)}
+	var validationErr *config.ValidationError
+	if err := cfg.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+	if len(validationErr.Problems) != 1 {
+		t.Errorf("Problems = %q, want 1 entry", validationErr.Problems)
+	}
+}
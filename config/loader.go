@@ -0,0 +1,150 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const envPrefix = "DDP_SENDER_"
+
+// setting describes a single overridable field, shared by flags and environment variables.
+type setting struct {
+	name  string // Flag name, the environment variable is derived from it.
+	usage string
+	apply func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"led-amount", "number of LEDs in the virtual canvas", intSetter(func(c *Config) *int { return &c.LEDAmount })},
+	{"ddp-endpoint", "DDP controller address (host:port)", stringSetter(func(c *Config) *string { return &c.DDPEndpoint })},
+	{"refresh-rate", "frame interval (e.g. 20ms)", durationSetter(func(c *Config) *Duration { return &c.RefreshRate })},
+	{"monitor-interval", "interval between throughput logs (e.g. 1s)", durationSetter(func(c *Config) *Duration { return &c.MonitorInterval })},
+	{"mappings-dir", "directory containing mapping files", stringSetter(func(c *Config) *string { return &c.MappingsDir })},
+	{"default-mapping", "mapping file loaded on startup", stringSetter(func(c *Config) *string { return &c.DefaultMapping })},
+	{"drums-dir", "directory containing drum kit files", stringSetter(func(c *Config) *string { return &c.DrumsDir })},
+	{"drum-kit", "drum kit loaded on startup", stringSetter(func(c *Config) *string { return &c.DrumKit })},
+	{"web-ui-port", "web UI and API port", intSetter(func(c *Config) *int { return &c.WebUIPort })},
+	{"web-ui-dir", "web UI directory used when the embedded files are unavailable", stringSetter(func(c *Config) *string { return &c.WebUIDir })},
+	{"midi-port", "UDP port of the MIDI listener", intSetter(func(c *Config) *int { return &c.MidiPort })},
+	{"midi-format", "format of the MIDI UDP datagrams (packet/raw)", stringSetter(func(c *Config) *string { return &c.MidiFormat })},
+	{"midi-tcp-port", "TCP port of the raw MIDI stream listener, 0 disables it", intSetter(func(c *Config) *int { return &c.MidiTCPPort })},
+	{"rtp-midi-port", "AppleMIDI session control port (data port is the next one), 0 disables it", intSetter(func(c *Config) *int { return &c.RTPMidiPort })},
+	{"rtp-midi-name", "AppleMIDI session name", stringSetter(func(c *Config) *string { return &c.RTPMidiName })},
+	{"osc-port", "UDP port of the OSC listener, 0 disables it", intSetter(func(c *Config) *int { return &c.OSCPort })},
+	{"reaper-port", "HTTP port of the REAPER mapping switch listener", intSetter(func(c *Config) *int { return &c.ReaperPort })},
+	{"blackout-on-exit", "send an all-black frame before exiting (true/false)", boolSetter(func(c *Config) *bool { return &c.BlackoutOnExit })},
+}
+
+// Load builds the configuration from defaults, the config file, the environment and args (without the program name).
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("ddp-sender", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a JSON config file (env "+envPrefix+"CONFIG)")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.name] = fs.String(s.name, "", fmt.Sprintf("%s (env %s)", s.usage, envName(s.name)))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv(envPrefix + "CONFIG")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []string
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(envName(s.name)); ok {
+			if err := s.apply(cfg, value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", envName(s.name), err))
+			}
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.name == f.Name {
+				if err := s.apply(cfg, *flagValues[s.name]); err != nil {
+					problems = append(problems, fmt.Sprintf("-%s: %v", s.name, err))
+				}
+			}
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err.(*ValidationError).Problems...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func intSetter(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func stringSetter(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func boolSetter(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func durationSetter(field func(c *Config) *Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*field(c) = Duration(parsed)
+		return nil
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// MasterConfig maps MIDI notes to the master brightness, blackout and freeze controls.
+type MasterConfig struct {
+	Channel        int      `json:"channel"`         // MIDI channel of the master notes, 0 disables them.
+	BrightnessNote int      `json:"brightness_note"` // Note on sets the brightness to velocity/127.
+	BlackoutNote   int      `json:"blackout_note"`   // Note on toggles an instant blackout.
+	FadeNote       int      `json:"fade_note"`       // Note on toggles a blackout faded over fade_time.
+	FreezeNote     int      `json:"freeze_note"`     // Note on toggles freeze frame.
+	BrightnessCC   int      `json:"brightness_cc"`   // Control change sets the brightness to value/127.
+	FadeTime       Duration `json:"fade_time"`
+}
+
+func defaultMaster() MasterConfig {
+	return MasterConfig{
+		BrightnessNote: 0,
+		BlackoutNote:   1,
+		FadeNote:       2,
+		FreezeNote:     3,
+		BrightnessCC:   7, // Channel volume.
+		FadeTime:       Duration(2 * time.Second),
+	}
+}
+
+func (m MasterConfig) validate() []string {
+	var problems []string
+	if m.Channel < 0 || m.Channel > 16 {
+		problems = append(problems, fmt.Sprintf("master.channel must be between 0 and 16 (got %d)", m.Channel))
+	}
+	notes := make(map[int]string)
+	for _, note := range []struct {
+		name  string
+		value int
+	}{{"brightness_note", m.BrightnessNote}, {"blackout_note", m.BlackoutNote}, {"fade_note", m.FadeNote}, {"freeze_note", m.FreezeNote}} {
+		if note.value < 0 || note.value > 127 {
+			problems = append(problems, fmt.Sprintf("master.%s must be between 0 and 127 (got %d)", note.name, note.value))
+		} else if other, ok := notes[note.value]; ok {
+			problems = append(problems, fmt.Sprintf("master.%s uses the same note as master.%s (%d)", note.name, other, note.value))
+		}
+		notes[note.value] = note.name
+	}
+	if m.BrightnessCC < 0 || m.BrightnessCC > 127 {
+		problems = append(problems, fmt.Sprintf("master.brightness_cc must be between 0 and 127 (got %d)", m.BrightnessCC))
+	}
+	if m.FadeTime.Duration() < 0 {
+		problems = append(problems, fmt.Sprintf("master.fade_time must not be negative (got %s)", m.FadeTime))
+	}
+	return problems
+}
@@ -0,0 +1,268 @@
+package config
+
+import "fmt"
+
+const (
+	PROTOCOL_DDP    = "ddp"
+	PROTOCOL_SACN   = "sacn"
+	PROTOCOL_ARTNET = "artnet"
+	PROTOCOL_WLED   = "wled"
+)
+
+const (
+	WLED_MODE_WARLS = "warls"
+	WLED_MODE_DRGB  = "drgb"
+	WLED_MODE_DNRGB = "dnrgb"
+)
+
+// OutputConfig assigns a slice of the LED canvas to a controller.
+type OutputConfig struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol,omitempty"` // Defaults to ddp.
+	Address  string `json:"address"`
+	Start    int    `json:"start"`            // First LED of the canvas.
+	Length   int    `json:"length,omitempty"` // Amount of LEDs, 0 means up to the end of the canvas.
+	Reverse  bool   `json:"reverse,omitempty"`
+	Offset   int    `json:"offset,omitempty"` // First pixel on the controller (ddp only).
+
+	Calibration string       `json:"calibration,omitempty"`  // Calibration profile name, defaults to legacy.
+	PixelFormat string       `json:"pixel_format,omitempty"` // Channel order of the LEDs (rgb, grb, rgbw...), defaults to rgb.
+	White       *WhiteConfig `json:"white,omitempty"`        // White extraction of rgbw and rgbwc pixels.
+	Power       *PowerConfig `json:"power,omitempty"`        // Current limiter, disabled without limit_milliamps.
+	BitDepth    int          `json:"bit_depth,omitempty"`    // Bits per channel sent to the controller, 8 (default) or 16.
+	Dither      bool         `json:"dither,omitempty"`       // Carry the quantization error of every channel to the next frame.
+
+	SACN   *SACNConfig   `json:"sacn,omitempty"`
+	ArtNet *ArtNetConfig `json:"artnet,omitempty"`
+	WLED   *WLEDConfig   `json:"wled,omitempty"`
+}
+
+// PowerConfig estimates the current drawn by the LEDs of an output and limits it to the power supply budget.
+type PowerConfig struct {
+	LimitMilliamps   float64 `json:"limit_milliamps,omitempty"`   // Budget of the power supply, 0 disables limiting.
+	ChannelMilliamps float64 `json:"channel_milliamps,omitempty"` // Draw of a color channel at full brightness, defaults to 20.
+	WhiteMilliamps   float64 `json:"white_milliamps,omitempty"`   // Draw of a white channel at full brightness, defaults to channel_milliamps.
+	IdleMilliamps    float64 `json:"idle_milliamps,omitempty"`    // Draw of a pixel with every channel off, defaults to 1.
+}
+
+// SACNConfig holds the E1.31 (sACN) specific output settings.
+type SACNConfig struct {
+	Universe   int    `json:"universe"`              // First universe, the segment spans as many as needed.
+	Priority   *int   `json:"priority,omitempty"`    // 0-200, defaults to 100.
+	Multicast  bool   `json:"multicast,omitempty"`   // Send to the universe multicast groups instead of Address.
+	SourceName string `json:"source_name,omitempty"` // Defaults to ddp-sender.
+}
+
+// ArtNetConfig holds the Art-Net (ArtDmx) specific output settings.
+type ArtNetConfig struct {
+	Net      int  `json:"net"`                // 0-127
+	Subnet   int  `json:"subnet"`             // 0-15
+	Universe int  `json:"universe"`           // 0-15, first universe, the segment spans as many as needed.
+	Sync     bool `json:"sync,omitempty"`     // Send ArtSync after each frame so nodes display all universes at once.
+	Discover bool `json:"discover,omitempty"` // Poll for nodes with ArtPoll, reported in the output stats.
+}
+
+// WLEDConfig holds the WLED realtime UDP specific output settings.
+type WLEDConfig struct {
+	Mode    string `json:"mode,omitempty"`    // warls, drgb or dnrgb (default).
+	Timeout int    `json:"timeout,omitempty"` // Seconds before WLED returns to its own preset, 255 never does. Defaults to 2.
+}
+
+// ResolvedOutputs returns the configured outputs with defaults applied.
+func (c *Config) ResolvedOutputs() []OutputConfig {
+	configured := c.Outputs
+	if len(configured) == 0 {
+		configured = []OutputConfig{{Name: "ddp", Address: c.DDPEndpoint}}
+	}
+	outputs := make([]OutputConfig, len(configured))
+	for i, output := range configured {
+		if output.Name == "" {
+			output.Name = fmt.Sprintf("output-%d", i)
+		}
+		if output.Protocol == "" {
+			output.Protocol = PROTOCOL_DDP
+		}
+		if output.Length == 0 {
+			output.Length = c.LEDAmount - output.Start
+		}
+		if output.Calibration == "" {
+			output.Calibration = CALIBRATION_LEGACY
+		}
+		if output.PixelFormat == "" {
+			output.PixelFormat = PIXEL_FORMAT_RGB
+		}
+		if output.BitDepth == 0 {
+			output.BitDepth = 8
+		}
+		white := WhiteConfig{}
+		if output.White != nil {
+			white = *output.White
+		}
+		white = white.withDefaults()
+		output.White = &white
+		power := PowerConfig{}
+		if output.Power != nil {
+			power = *output.Power
+		}
+		if power.ChannelMilliamps == 0 {
+			power.ChannelMilliamps = 20
+		}
+		if power.WhiteMilliamps == 0 {
+			power.WhiteMilliamps = power.ChannelMilliamps
+		}
+		if power.IdleMilliamps == 0 {
+			power.IdleMilliamps = 1
+		}
+		output.Power = &power
+		if output.Protocol == PROTOCOL_SACN {
+			sacn := SACNConfig{Universe: 1, SourceName: "ddp-sender"}
+			if output.SACN != nil {
+				sacn = *output.SACN
+				if sacn.SourceName == "" {
+					sacn.SourceName = "ddp-sender"
+				}
+			}
+			if sacn.Priority == nil {
+				priority := 100
+				sacn.Priority = &priority
+			}
+			output.SACN = &sacn
+		}
+		if output.Protocol == PROTOCOL_ARTNET && output.ArtNet == nil {
+			output.ArtNet = &ArtNetConfig{}
+		}
+		if output.Protocol == PROTOCOL_WLED {
+			wled := WLEDConfig{}
+			if output.WLED != nil {
+				wled = *output.WLED
+			}
+			if wled.Mode == "" {
+				wled.Mode = WLED_MODE_DNRGB
+			}
+			if wled.Timeout == 0 {
+				wled.Timeout = 2
+			}
+			output.WLED = &wled
+		}
+		outputs[i] = output
+	}
+	return outputs
+}
+
+// universes returns the amount of DMX universes spanned by the segment, no pixel is split
+// between two universes.
+func (o OutputConfig) universes() int {
+	pixelSize := len(o.PixelFormat) * max(1, o.BitDepth/8)
+	slots := 512 / pixelSize * pixelSize
+	return max(1, (o.Length*pixelSize+slots-1)/slots)
+}
+
+func (c *Config) validateOutputs() []string {
+	var problems []string
+	names := make(map[string]bool)
+	for i, output := range c.ResolvedOutputs() {
+		field := fmt.Sprintf("outputs[%d]", i)
+		if names[output.Name] {
+			problems = append(problems, fmt.Sprintf("%s name %q is used by another output", field, output.Name))
+		}
+		names[output.Name] = true
+		if output.Start < 0 || output.Length <= 0 || output.Start+output.Length > c.LEDAmount {
+			problems = append(problems, fmt.Sprintf("%s segment %d+%d is outside the %d LED canvas", field, output.Start, output.Length, c.LEDAmount))
+		}
+		if output.Offset < 0 {
+			problems = append(problems, fmt.Sprintf("%s offset must not be negative (got %d)", field, output.Offset))
+		}
+		if _, ok := c.Calibration(output.Calibration); !ok {
+			problems = append(problems, fmt.Sprintf("%s calibration %q is not defined", field, output.Calibration))
+		}
+		if err := validatePixelFormat(output.PixelFormat); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %v", field, err))
+		}
+		problems = append(problems, output.White.validate(field)...)
+		if output.BitDepth != 8 && output.BitDepth != 16 {
+			problems = append(problems, fmt.Sprintf("%s bit_depth must be 8 or 16 (got %d)", field, output.BitDepth))
+		}
+		if power := output.Power; power.LimitMilliamps < 0 || power.ChannelMilliamps < 0 || power.WhiteMilliamps < 0 || power.IdleMilliamps < 0 {
+			problems = append(problems, fmt.Sprintf("%s power values must not be negative", field))
+		} else if power.LimitMilliamps > 0 && power.LimitMilliamps < power.IdleMilliamps*float64(output.Length) {
+			problems = append(problems, fmt.Sprintf("%s power.limit_milliamps %g is below the idle draw of %d LEDs", field, power.LimitMilliamps, output.Length))
+		}
+
+		switch output.Protocol {
+		case PROTOCOL_DDP:
+			if err := validateHostPort(output.Address); err != nil {
+				problems = append(problems, fmt.Sprintf("%s address %v", field, err))
+			}
+		case PROTOCOL_SACN:
+			if !output.SACN.Multicast {
+				if err := validateHostPort(output.Address); err != nil {
+					problems = append(problems, fmt.Sprintf("%s address %v", field, err))
+				}
+			}
+			if output.Offset != 0 {
+				problems = append(problems, fmt.Sprintf("%s offset is not supported by sacn outputs", field))
+			}
+			if last := output.SACN.Universe + output.universes() - 1; output.SACN.Universe < 1 || last > 63999 {
+				problems = append(problems, fmt.Sprintf("%s sacn universes must be between 1 and 63999 (got %d-%d)", field, output.SACN.Universe, last))
+			}
+			if *output.SACN.Priority < 0 || *output.SACN.Priority > 200 {
+				problems = append(problems, fmt.Sprintf("%s sacn.priority must be between 0 and 200 (got %d)", field, *output.SACN.Priority))
+			}
+		case PROTOCOL_ARTNET:
+			if err := validateHostPort(output.Address); err != nil {
+				problems = append(problems, fmt.Sprintf("%s address %v", field, err))
+			}
+			if output.Offset != 0 {
+				problems = append(problems, fmt.Sprintf("%s offset is not supported by artnet outputs", field))
+			}
+			artnet := output.ArtNet
+			if artnet.Net < 0 || artnet.Net > 127 {
+				problems = append(problems, fmt.Sprintf("%s artnet.net must be between 0 and 127 (got %d)", field, artnet.Net))
+			}
+			if artnet.Subnet < 0 || artnet.Subnet > 15 {
+				problems = append(problems, fmt.Sprintf("%s artnet.subnet must be between 0 and 15 (got %d)", field, artnet.Subnet))
+			}
+			if artnet.Universe < 0 || artnet.Universe > 15 {
+				problems = append(problems, fmt.Sprintf("%s artnet.universe must be between 0 and 15 (got %d)", field, artnet.Universe))
+			}
+			// The segment spans the next Port-Addresses, carrying into the subnet and net.
+			first := artnet.Net<<8 | artnet.Subnet<<4 | artnet.Universe
+			if last := first + output.universes() - 1; last > 0x7FFF {
+				problems = append(problems, fmt.Sprintf("%s artnet universes must end at Port-Address 32767 (got %d-%d)", field, first, last))
+			}
+		case PROTOCOL_WLED:
+			if err := validateHost(output.Address); err != nil {
+				problems = append(problems, fmt.Sprintf("%s address %v", field, err))
+			}
+			// WLED applies the color order and white extraction of its own LED settings.
+			if output.PixelFormat != PIXEL_FORMAT_RGB {
+				problems = append(problems, fmt.Sprintf("%s pixel_format must be rgb for wled outputs, set the color order in WLED", field))
+			}
+			if output.BitDepth != 8 {
+				problems = append(problems, fmt.Sprintf("%s bit_depth must be 8 for wled outputs", field))
+			}
+			if output.WLED.Timeout < 1 || output.WLED.Timeout > 255 {
+				problems = append(problems, fmt.Sprintf("%s wled.timeout must be between 1 and 255 (got %d)", field, output.WLED.Timeout))
+			}
+			switch output.WLED.Mode {
+			case WLED_MODE_WARLS:
+				if output.Offset+output.Length > 256 {
+					problems = append(problems, fmt.Sprintf("%s warls can only address the first 256 LEDs (offset %d + length %d)", field, output.Offset, output.Length))
+				}
+			case WLED_MODE_DRGB:
+				if output.Offset != 0 {
+					problems = append(problems, fmt.Sprintf("%s offset is not supported by drgb, use dnrgb", field))
+				}
+				if output.Length > 490 {
+					problems = append(problems, fmt.Sprintf("%s drgb supports up to 490 LEDs (got %d), use dnrgb", field, output.Length))
+				}
+			case WLED_MODE_DNRGB:
+			default:
+				problems = append(problems, fmt.Sprintf("%s wled.mode %q is not supported", field, output.WLED.Mode))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s protocol %q is not supported", field, output.Protocol))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pixel formats list the channels of every LED in the order the controller expects them:
+// r, g and b for the colors, w for the (warm) white LED and c for the cool white LED.
+const (
+	PIXEL_FORMAT_RGB   = "rgb"
+	PIXEL_FORMAT_GRB   = "grb"
+	PIXEL_FORMAT_RGBW  = "rgbw"
+	PIXEL_FORMAT_GRBW  = "grbw"
+	PIXEL_FORMAT_RGBWC = "rgbwc"
+)
+
+const (
+	WHITE_STRATEGY_NONE        = "none"        // White LEDs stay off.
+	WHITE_STRATEGY_MIN         = "min"         // The common part of r, g and b moves to the w LED.
+	WHITE_STRATEGY_TEMPERATURE = "temperature" // Like min, using the tint of the white LEDs.
+)
+
+// WhiteConfig selects how the white channels of rgbw and rgbwc pixels are derived from the color.
+type WhiteConfig struct {
+	Strategy        string `json:"strategy,omitempty"`         // none, min (default) or temperature.
+	Temperature     int    `json:"temperature,omitempty"`      // Kelvin of the w LEDs, defaults to 4500.
+	CoolTemperature int    `json:"cool_temperature,omitempty"` // Kelvin of the c LEDs, defaults to 6500.
+}
+
+func (w WhiteConfig) withDefaults() WhiteConfig {
+	if w.Strategy == "" {
+		w.Strategy = WHITE_STRATEGY_MIN
+	}
+	if w.Temperature == 0 {
+		w.Temperature = 4500
+	}
+	if w.CoolTemperature == 0 {
+		w.CoolTemperature = 6500
+	}
+	return w
+}
+
+// validatePixelFormat checks format holds r, g and b once, optionally w, and c only next to w.
+func validatePixelFormat(format string) error {
+	for _, channel := range format {
+		if !strings.ContainsRune("rgbwc", channel) || strings.Count(format, string(channel)) > 1 {
+			return fmt.Errorf("pixel_format %q must list the channels r, g, b and optionally w and c once", format)
+		}
+	}
+	if !strings.Contains(format, "r") || !strings.Contains(format, "g") || !strings.Contains(format, "b") {
+		return fmt.Errorf("pixel_format %q must contain r, g and b", format)
+	}
+	if strings.Contains(format, "c") && !strings.Contains(format, "w") {
+		return fmt.Errorf("pixel_format %q uses c without w", format)
+	}
+	return nil
+}
+
+func (w WhiteConfig) validate(field string) []string {
+	var problems []string
+	switch w.Strategy {
+	case WHITE_STRATEGY_NONE, WHITE_STRATEGY_MIN, WHITE_STRATEGY_TEMPERATURE:
+	default:
+		problems = append(problems, fmt.Sprintf("%s white.strategy %q is not supported", field, w.Strategy))
+	}
+	if w.Temperature < 1000 || w.Temperature > 40000 {
+		problems = append(problems, fmt.Sprintf("%s white.temperature must be between 1000 and 40000 (got %d)", field, w.Temperature))
+	}
+	if w.CoolTemperature < 1000 || w.CoolTemperature > 40000 {
+		problems = append(problems, fmt.Sprintf("%s white.cool_temperature must be between 1000 and 40000 (got %d)", field, w.CoolTemperature))
+	}
+	return problems
+}
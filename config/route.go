@@ -0,0 +1,68 @@
+package config
+
+import "fmt"
+
+// Route handlers: the note LEDs, a drum kit profile or a custom mapping file.
+const (
+	HANDLER_DIRECT  = "direct"
+	HANDLER_DRUMS   = "drums"
+	HANDLER_MAPPING = "mapping"
+)
+
+// RouteConfig sends the messages of a MIDI channel to one or more handlers.
+// Every route matching a message handles it.
+type RouteConfig struct {
+	Channel  int             `json:"channel"`         // 1-16
+	Notes    *NoteRange      `json:"notes,omitempty"` // Only route these notes, other messages than notes and poly aftertouch always match.
+	Handlers []HandlerConfig `json:"handlers"`
+}
+
+// NoteRange holds the notes from Low to High, both included.
+type NoteRange struct {
+	Low  int `json:"low"`
+	High int `json:"high"`
+}
+
+// HandlerConfig names a handler of a route. Without file, drums and mapping follow the kit
+// and mapping switched from the web UI, REAPER and program changes. With a file they get their
+// own mapper, shared by the routes naming the same file.
+type HandlerConfig struct {
+	Type string `json:"type"`           // direct, drums or mapping.
+	File string `json:"file,omitempty"` // Drum kit in drums_dir or mapping in mappings_dir.
+}
+
+// defaultRoutes sends channel 1 to the note LEDs, 2 to the drum kit and 3 to the custom mapping.
+func defaultRoutes() []RouteConfig {
+	return []RouteConfig{
+		{Channel: 1, Handlers: []HandlerConfig{{Type: HANDLER_DIRECT}}},
+		{Channel: 2, Handlers: []HandlerConfig{{Type: HANDLER_DRUMS}}},
+		{Channel: 3, Handlers: []HandlerConfig{{Type: HANDLER_MAPPING}}},
+	}
+}
+
+func (r RouteConfig) validate(i int) []string {
+	var problems []string
+	if r.Channel < 1 || r.Channel > 16 {
+		problems = append(problems, fmt.Sprintf("routes[%d].channel must be between 1 and 16 (got %d)", i, r.Channel))
+	}
+	if r.Notes != nil {
+		if r.Notes.Low < 0 || r.Notes.High > 127 || r.Notes.Low > r.Notes.High {
+			problems = append(problems, fmt.Sprintf("routes[%d].notes must satisfy 0 <= low <= high <= 127 (got %d-%d)", i, r.Notes.Low, r.Notes.High))
+		}
+	}
+	if len(r.Handlers) == 0 {
+		problems = append(problems, fmt.Sprintf("routes[%d].handlers must not be empty", i))
+	}
+	for j, handler := range r.Handlers {
+		switch handler.Type {
+		case HANDLER_DIRECT:
+			if handler.File != "" {
+				problems = append(problems, fmt.Sprintf("routes[%d].handlers[%d].file is not used by %s", i, j, HANDLER_DIRECT))
+			}
+		case HANDLER_DRUMS, HANDLER_MAPPING:
+		default:
+			problems = append(problems, fmt.Sprintf("routes[%d].handlers[%d].type must be %s, %s or %s (got %q)", i, j, HANDLER_DIRECT, HANDLER_DRUMS, HANDLER_MAPPING, handler.Type))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,34 @@
+package config
+
+import "fmt"
+
+// Mapping switch transitions, from the effects of the old mapping to the new one.
+const (
+	TRANSITION_CUT       = "cut"       // End the running effects at once.
+	TRANSITION_FINISH    = "finish"    // Release the running effects and let them end on their own.
+	TRANSITION_CROSSFADE = "crossfade" // Fade the running effects out while the new ones fade in.
+	TRANSITION_BLACK     = "black"     // Fade the running effects out, then the new ones in.
+)
+
+// TransitionConfig is how the running effects hand over to a new mapping.
+type TransitionConfig struct {
+	Type     string   `json:"type"`
+	Duration Duration `json:"duration,omitempty"` // Whole fade of crossfade and black, black spends half of it fading out.
+}
+
+func defaultTransition() TransitionConfig {
+	return TransitionConfig{Type: TRANSITION_CUT}
+}
+
+// Validate checks the type and duration of the transition.
+func (t TransitionConfig) Validate() error {
+	switch t.Type {
+	case TRANSITION_CUT, TRANSITION_FINISH, TRANSITION_CROSSFADE, TRANSITION_BLACK:
+	default:
+		return fmt.Errorf("type must be %s, %s, %s or %s (got %q)", TRANSITION_CUT, TRANSITION_FINISH, TRANSITION_CROSSFADE, TRANSITION_BLACK, t.Type)
+	}
+	if t.Duration.Duration() < 0 {
+		return fmt.Errorf("duration must not be negative (got %s)", t.Duration)
+	}
+	return nil
+}
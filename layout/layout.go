@@ -0,0 +1,237 @@
+// Package layout describes where the LEDs of the canvas are physically placed, so
+// effects can be addressed by position while outputs keep receiving the linear canvas.
+package layout
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Config places the LEDs of the canvas. LEDs not covered by any element stay on a
+// linear strip along the x axis (LED i at x=i, y=0).
+type Config struct {
+	Matrices   []Matrix        `json:"matrices,omitempty"`
+	Strips     []Strip         `json:"strips,omitempty"`
+	Pixels     []Pixel         `json:"pixels,omitempty"`
+	PixelsFile string          `json:"pixels_file,omitempty"` // JSON list of pixels, added to Pixels.
+	Zones      map[string]Zone `json:"zones,omitempty"`       // Global zones every mapping file can use.
+}
+
+// Matrix is a grid of LEDs wired one row (or column) after the other.
+type Matrix struct {
+	Start      int     `json:"start"` // First LED of the canvas.
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	X          float64 `json:"x"` // Position of the top-left LED.
+	Y          float64 `json:"y"`
+	Vertical   bool    `json:"vertical,omitempty"`   // Wired column by column instead of row by row.
+	Serpentine bool    `json:"serpentine,omitempty"` // Every other row (or column) runs backwards.
+}
+
+// Strip is a straight line of LEDs starting at X, Y and moving DX, DY per LED.
+type Strip struct {
+	Start  int     `json:"start"` // First LED of the canvas.
+	Length int     `json:"length"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	DX     float64 `json:"dx,omitempty"` // Defaults to 1 when DX and DY are both 0.
+	DY     float64 `json:"dy,omitempty"`
+}
+
+// Pixel places a single LED of the canvas.
+type Pixel struct {
+	Index int     `json:"index"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+}
+
+// Point is the position of an LED.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Rect selects the LEDs with X <= x < X+Width and Y <= y < Y+Height.
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (r Rect) Contains(p Point) bool {
+	return p.X >= r.X && p.X < r.X+r.Width && p.Y >= r.Y && p.Y < r.Y+r.Height
+}
+
+// Layout is the position of every LED of the canvas.
+type Layout struct {
+	points []Point
+	zones  map[string]Zone
+}
+
+// New places amount LEDs as described by cfg, reading the pixels file if any.
+func New(cfg Config, amount int) (*Layout, error) {
+	if cfg.PixelsFile != "" {
+		data, err := os.ReadFile(cfg.PixelsFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading layout pixels file: %w", err)
+		}
+		var pixels []Pixel
+		if err := json.Unmarshal(data, &pixels); err != nil {
+			return nil, fmt.Errorf("parsing layout pixels file %s: %w", cfg.PixelsFile, err)
+		}
+		cfg.Pixels = append(slices.Clone(cfg.Pixels), pixels...)
+	}
+	if problems := cfg.Validate(amount); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid layout: %s", strings.Join(problems, "; "))
+	}
+
+	l := &Layout{points: make([]Point, amount), zones: cfg.Zones}
+	for i := range l.points {
+		l.points[i] = Point{X: float64(i)}
+	}
+	for _, matrix := range cfg.Matrices {
+		for i := 0; i < matrix.Width*matrix.Height; i++ {
+			l.points[matrix.Start+i] = matrix.point(i)
+		}
+	}
+	for _, strip := range cfg.Strips {
+		dx, dy := strip.direction()
+		for i := 0; i < strip.Length; i++ {
+			l.points[strip.Start+i] = Point{X: strip.X + float64(i)*dx, Y: strip.Y + float64(i)*dy}
+		}
+	}
+	for _, pixel := range cfg.Pixels {
+		l.points[pixel.Index] = Point{X: pixel.X, Y: pixel.Y}
+	}
+	return l, nil
+}
+
+// point returns the position of the i-th LED of the matrix in wiring order.
+func (m Matrix) point(i int) Point {
+	length := m.Width
+	if m.Vertical {
+		length = m.Height
+	}
+	line, offset := i/length, i%length
+	if m.Serpentine && line%2 == 1 {
+		offset = length - 1 - offset
+	}
+	if m.Vertical {
+		return Point{X: m.X + float64(line), Y: m.Y + float64(offset)}
+	}
+	return Point{X: m.X + float64(offset), Y: m.Y + float64(line)}
+}
+
+func (s Strip) direction() (float64, float64) {
+	if s.DX == 0 && s.DY == 0 {
+		return 1, 0
+	}
+	return s.DX, s.DY
+}
+
+// Validate checks that every element fits in a canvas of amount LEDs without overlapping another one.
+func (c Config) Validate(amount int) []string {
+	var problems []string
+	owner := make([]string, amount)
+	claim := func(field string, start, length int) {
+		if start < 0 || length <= 0 || start+length > amount {
+			problems = append(problems, fmt.Sprintf("%s LEDs %d+%d are outside the %d LED canvas", field, start, length, amount))
+			return
+		}
+		for i := start; i < start+length; i++ {
+			if owner[i] != "" {
+				problems = append(problems, fmt.Sprintf("%s LED %d is already placed by %s", field, i, owner[i]))
+				return
+			}
+			owner[i] = field
+		}
+	}
+
+	for i, matrix := range c.Matrices {
+		field := fmt.Sprintf("layout.matrices[%d]", i)
+		if matrix.Width <= 0 || matrix.Height <= 0 {
+			problems = append(problems, fmt.Sprintf("%s width and height must be positive (got %dx%d)", field, matrix.Width, matrix.Height))
+			continue
+		}
+		claim(field, matrix.Start, matrix.Width*matrix.Height)
+	}
+	for i, strip := range c.Strips {
+		claim(fmt.Sprintf("layout.strips[%d]", i), strip.Start, strip.Length)
+	}
+	for i, pixel := range c.Pixels {
+		claim(fmt.Sprintf("layout.pixels[%d]", i), pixel.Index, 1)
+	}
+
+	problems = append(problems, validateZones("layout.zones", c.Zones)...)
+	return problems
+}
+
+// Len returns the amount of LEDs of the canvas.
+func (l *Layout) Len() int {
+	return len(l.points)
+}
+
+// Point returns the position of an LED of the canvas.
+func (l *Layout) Point(index int) Point {
+	return l.points[index]
+}
+
+// Points returns the position of every LED, indexed by canvas position.
+func (l *Layout) Points() []Point {
+	return slices.Clone(l.points)
+}
+
+// At returns the LED closest to x, y if one is within half a unit on both axes.
+func (l *Layout) At(x, y float64) (int, bool) {
+	best, bestDistance := -1, math.Inf(1)
+	for i, p := range l.points {
+		dx, dy := math.Abs(p.X-x), math.Abs(p.Y-y)
+		if dx > 0.5 || dy > 0.5 {
+			continue
+		}
+		if distance := dx*dx + dy*dy; distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best, best >= 0
+}
+
+// Rect returns the LEDs inside r, top to bottom and left to right so effects
+// walk the rectangle like lines of text.
+func (l *Layout) Rect(r Rect) []int {
+	var indexes []int
+	for i, p := range l.points {
+		if r.Contains(p) {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		pa, pb := l.points[indexes[a]], l.points[indexes[b]]
+		if pa.Y != pb.Y {
+			return pa.Y < pb.Y
+		}
+		return pa.X < pb.X
+	})
+	return indexes
+}
+
+// Bounds returns the smallest rectangle containing every LED.
+func (l *Layout) Bounds() Rect {
+	if len(l.points) == 0 {
+		return Rect{}
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range l.points {
+		minX, minY = min(minX, p.X), min(minY, p.Y)
+		maxX, maxY = max(maxX, p.X), max(maxY, p.Y)
+	}
+	// Rect excludes its far edges, include the last LEDs.
+	return Rect{X: minX, Y: minY, Width: maxX - minX + 1, Height: maxY - minY + 1}
+}
@@ -0,0 +1,130 @@
+package layout_test
+
+import (
+	"ddp-sender/layout"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNew_SerpentineMatrix(t *testing.T) {
+	// 3x2 matrix after 2 linear LEDs:
+	//   2 3 4
+	//   7 6 5
+	l, err := layout.New(layout.Config{
+		Matrices: []layout.Matrix{{Start: 2, Width: 3, Height: 2, X: 10, Serpentine: true}},
+	}, 9)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		index int
+		want  layout.Point
+	}{
+		{0, layout.Point{X: 0}},
+		{2, layout.Point{X: 10}},
+		{4, layout.Point{X: 12}},
+		{5, layout.Point{X: 12, Y: 1}},
+		{7, layout.Point{X: 10, Y: 1}},
+		{8, layout.Point{X: 8}},
+	}
+	for _, tt := range tests {
+		if got := l.Point(tt.index); got != tt.want {
+			t.Errorf("Point(%d) = %v, want %v", tt.index, got, tt.want)
+		}
+	}
+	if index, ok := l.At(11, 1); !ok || index != 6 {
+		t.Errorf("At(11, 1) = %d, %t, want 6", index, ok)
+	}
+}
+
+func TestLayout_RectIsRowMajor(t *testing.T) {
+	l, err := layout.New(layout.Config{
+		Matrices: []layout.Matrix{{Width: 3, Height: 3, Serpentine: true}},
+		Zones:    map[string]layout.Zone{"right": {Rects: []layout.Rect{{X: 1, Y: 0, Width: 2, Height: 2}}}},
+	}, 9)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	want := []int{1, 2, 4, 3}
+	if got := l.Rect(layout.Rect{X: 1, Width: 2, Height: 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rect() = %v, want %v", got, want)
+	}
+	if got, err := l.Zone("right", nil); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Zone(right) = %v, %v, want %v", got, err, want)
+	}
+	if _, err := l.Zone("missing", nil); err == nil {
+		t.Error("Zone(missing) error = nil, want unknown zone")
+	}
+}
+
+func TestNew_PixelsFileAndStrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pixels.json")
+	if err := os.WriteFile(path, []byte(`[{"index": 4, "x": 2.5, "y": -1}]`), 0644); err != nil {
+		t.Fatalf("Failed to write pixels file: %v", err)
+	}
+
+	l, err := layout.New(layout.Config{
+		Strips:     []layout.Strip{{Start: 0, Length: 4, X: 5, Y: 3, DY: -1}},
+		PixelsFile: path,
+	}, 5)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := l.Point(3); got != (layout.Point{X: 5, Y: 0}) {
+		t.Errorf("Point(3) = %v, want {5 0}", got)
+	}
+	if got := l.Point(4); got != (layout.Point{X: 2.5, Y: -1}) {
+		t.Errorf("Point(4) = %v, want {2.5 -1}", got)
+	}
+}
+
+func TestConfig_ValidateOverlapAndBounds(t *testing.T) {
+	problems := layout.Config{
+		Matrices: []layout.Matrix{{Start: 0, Width: 4, Height: 2}},
+		Strips:   []layout.Strip{{Start: 6, Length: 4}, {Start: 12, Length: 2}},
+		Zones: map[string]layout.Zone{
+			"empty": {},
+			"loop":  {Zones: []string{"other"}},
+			"other": {Zones: []string{"loop", "missing"}},
+		},
+	}.Validate(12)
+	// Overlap, outside the canvas, empty zone, unknown zone and a loop reported from both zones.
+	if len(problems) != 6 {
+		t.Errorf("Validate() = %q, want 6 problems", problems)
+	}
+}
+
+func TestLayout_ZoneUnionAndShadowing(t *testing.T) {
+	l, err := layout.New(layout.Config{
+		Zones: map[string]layout.Zone{
+			"left":  {Ranges: []layout.Range{{First: 0, Last: 3}}},
+			"right": {Ranges: []layout.Range{{First: 9, Last: 6, Step: -1}}},
+			"both":  {Zones: []string{"left", "right"}, Ranges: []layout.Range{{First: 2, Last: 4}}},
+		},
+	}, 10)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Ranges come first, LEDs selected twice keep their first position.
+	want := []int{2, 3, 0, 1, 9, 8, 7}
+	if got, err := l.Zone("both", nil); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Zone(both) = %v, %v, want %v", got, err, want)
+	}
+
+	// Mapping zones shadow the global ones, also when referenced by a global zone.
+	local := map[string]layout.Zone{"left": {Ranges: []layout.Range{{First: 5, Last: 6}}}}
+	want = []int{2, 3, 5, 9, 8, 7}
+	if got, err := l.Zone("both", local); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Zone(both, local) = %v, %v, want %v", got, err, want)
+	}
+
+	local = map[string]layout.Zone{"left": {Zones: []string{"both"}}}
+	if _, err := l.Zone("both", local); err == nil {
+		t.Error("Zone(both) with a loop error = nil, want error")
+	}
+}
@@ -0,0 +1,155 @@
+package layout
+
+import (
+	"ddp-sender/util"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Range selects the LEDs of the canvas from First to Last (excluded) every Step, like util.MakeRange.
+type Range struct {
+	First int `json:"first"`
+	Last  int `json:"last"`
+	Step  int `json:"step,omitempty"` // Defaults to 1.
+}
+
+func (r Range) leds() []int {
+	step := r.Step
+	if step == 0 {
+		step = 1
+	}
+	return util.MakeRange(r.First, r.Last, step)
+}
+
+// Zone is a named set of LEDs: the union of its ranges, rectangles and other zones,
+// in that order. LEDs selected twice keep their first position.
+type Zone struct {
+	Ranges []Range  `json:"ranges,omitempty"`
+	Rects  []Rect   `json:"rects,omitempty"`
+	Zones  []string `json:"zones,omitempty"` // Other zones, looked up like Layout.Zone.
+}
+
+// Validate checks the zone on its own, references to other zones are checked when resolving it.
+func (z Zone) Validate() error {
+	if len(z.Ranges) == 0 && len(z.Rects) == 0 && len(z.Zones) == 0 {
+		return fmt.Errorf("zone is empty")
+	}
+	for _, rect := range z.Rects {
+		if rect.Width <= 0 || rect.Height <= 0 {
+			return fmt.Errorf("rect width and height must be positive (got %gx%g)", rect.Width, rect.Height)
+		}
+	}
+	return nil
+}
+
+// validateZones checks every zone and that references between them exist and do not loop.
+func validateZones(field string, zones map[string]Zone) []string {
+	var problems []string
+	names := make([]string, 0, len(zones))
+	for name := range zones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		zone := zones[name]
+		if err := zone.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.%s %v", field, name, err))
+			continue
+		}
+		for _, other := range zone.Zones {
+			if _, ok := zones[other]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s references unknown zone %q", field, name, other))
+			}
+		}
+		if path := zoneCycle(name, zones); path != nil {
+			problems = append(problems, fmt.Sprintf("%s.%s references itself (%s)", field, name, strings.Join(path, " -> ")))
+		}
+	}
+	return problems
+}
+
+// zoneCycle returns the references leading from start back to itself, nil if there are none.
+func zoneCycle(start string, zones map[string]Zone) []string {
+	visited := make(map[string]bool)
+	var walk func(name string, path []string) []string
+	walk = func(name string, path []string) []string {
+		for _, other := range zones[name].Zones {
+			next := append(slices.Clone(path), other)
+			if other == start {
+				return next
+			}
+			if visited[other] {
+				continue
+			}
+			visited[other] = true
+			if cycle := walk(other, next); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk(start, []string{start})
+}
+
+// Zone returns the LEDs of a named zone. Names are looked up in local first, then in
+// the global zones of the layout, so a mapping file can shadow a global zone.
+func (l *Layout) Zone(name string, local map[string]Zone) ([]int, error) {
+	return l.resolveName(name, local, nil)
+}
+
+// Resolve returns the LEDs of zone, looking up the zones it references like Zone.
+func (l *Layout) Resolve(zone Zone, local map[string]Zone) ([]int, error) {
+	return l.resolve(zone, local, nil)
+}
+
+// Zones returns the global zones of the layout.
+func (l *Layout) Zones() map[string]Zone {
+	zones := make(map[string]Zone, len(l.zones))
+	for name, zone := range l.zones {
+		zones[name] = zone
+	}
+	return zones
+}
+
+func (l *Layout) resolveName(name string, local map[string]Zone, path []string) ([]int, error) {
+	if slices.Contains(path, name) {
+		return nil, fmt.Errorf("zone %q references itself (%s)", name, strings.Join(append(path, name), " -> "))
+	}
+	zone, ok := local[name]
+	if !ok {
+		zone, ok = l.zones[name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown zone %q", name)
+	}
+	return l.resolve(zone, local, append(path, name))
+}
+
+func (l *Layout) resolve(zone Zone, local map[string]Zone, path []string) ([]int, error) {
+	var leds []int
+	seen := make(map[int]bool)
+	add := func(indexes []int) {
+		for _, index := range indexes {
+			if !seen[index] {
+				seen[index] = true
+				leds = append(leds, index)
+			}
+		}
+	}
+	for _, r := range zone.Ranges {
+		add(r.leds())
+	}
+	for _, rect := range zone.Rects {
+		add(l.Rect(rect))
+	}
+	for _, name := range zone.Zones {
+		indexes, err := l.resolveName(name, local, slices.Clone(path))
+		if err != nil {
+			return nil, err
+		}
+		add(indexes)
+	}
+	return leds, nil
+}
@@ -0,0 +1,45 @@
+package led
+
+import (
+	"ddp-sender/updater/effects"
+	"time"
+)
+
+// fade scales the contribution of an effect to the frame from level from to level to.
+type fade struct {
+	from     float64
+	to       float64
+	start    time.Time
+	duration time.Duration
+}
+
+// level returns the fade level at now, from until start.
+func (f fade) level(now time.Time) float64 {
+	elapsed := now.Sub(f.start)
+	switch {
+	case elapsed <= 0:
+		return f.from
+	case f.duration <= 0 || elapsed >= f.duration:
+		return f.to
+	}
+	return f.from + (f.to-f.from)*float64(elapsed)/float64(f.duration)
+}
+
+// done reports whether the fade ended at level 0, which ends its effect.
+func (f fade) done(now time.Time) bool {
+	return f.to == 0 && now.Sub(f.start) >= f.duration
+}
+
+// FadeEffect fades a running effect from level from to level to over duration, after delay.
+// At level 0 the effect is not drawn, at level 1 it is drawn as usual; an effect faded out
+// to 0 ends. The layers below show through in between, whatever the blend mode.
+func (a *LEDArrayColor) FadeEffect(effect effects.Effect, from, to float64, delay, duration time.Duration) {
+	a.effectsMutex.Lock()
+	defer a.effectsMutex.Unlock()
+	a.fades[effect] = fade{
+		from:     max(0, min(1, from)),
+		to:       max(0, min(1, to)),
+		start:    time.Now().Add(delay),
+		duration: duration,
+	}
+}
@@ -82,8 +82,7 @@ type LED struct {
 func (l *LED) Get() []byte {
 	l.RLock()
 	defer l.RUnlock()
-	r, g, b := colorCorrection(l.red, l.green, l.blue)
-	return []byte{byte(r), byte(g), byte(b)}
+	return []byte{l.red, l.green, l.blue}
 }
 
 func (l *LED) Set(red, green, blue uint8) {
@@ -3,135 +3,193 @@ package led
 import (
 	"ddp-sender/updater/effects"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/lucasb-eyer/go-colorful"
 )
 
+// HELD_LAYER is the layer of the pixels set with SetLED and SetLEDs: they are composed
+// below the effects of that layer and above the effects of lower layers.
+const HELD_LAYER = 0
+
+// LEDArrayColor renders effects into a back buffer that is swapped with the front
+// buffer once the frame is complete, so readers always see a whole frame.
 type LEDArrayColor struct {
-	leds         []*LEDColor
+	amount       int
+	back         []colorful.Color // Only used by SetNextEffectValues.
+	front        []colorful.Color // Last complete frame, guarded by frameMutex.
+	frameMutex   sync.RWMutex
+	held         []colorful.Color // Pixels held until switched off, black is unset.
+	heldMutex    sync.RWMutex
 	effects      []effects.Effect
+	fades        map[effects.Effect]fade // Effects drawn at a fading level, guarded by effectsMutex.
 	effectsMutex sync.RWMutex
+	master       master
+	masterMutex  sync.Mutex
 }
 
 func (a *LEDArrayColor) GetArray() []byte {
 	start := time.Now()
-	result := make([]byte, 0, len(a.leds))
-	for _, led := range a.leds {
-		result = append(result, led.Get()...)
-	}
+	result := a.AppendArray(make([]byte, 0, 3*a.amount))
 	if time.Since(start) > 3*time.Millisecond {
 		log.Printf("GetArray() -> %s\n", time.Since(start))
 	}
 	return result
 }
 
+// AppendArray appends the current frame as bytes to dst, allowing callers to reuse a buffer.
+// Calibration is applied per output, the bytes are the uncorrected composition.
+func (a *LEDArrayColor) AppendArray(dst []byte) []byte {
+	a.frameMutex.RLock()
+	defer a.frameMutex.RUnlock()
+	for _, color := range a.front {
+		r, g, b := color.RGB255()
+		dst = append(dst, r, g, b)
+	}
+	return dst
+}
+
+// GetFrame copies the current frame into dst, reusing its capacity.
+func (a *LEDArrayColor) GetFrame(dst []colorful.Color) []colorful.Color {
+	a.frameMutex.RLock()
+	defer a.frameMutex.RUnlock()
+	return append(dst[:0], a.front...)
+}
+
 func (a *LEDArrayColor) SetNextEffectValues() {
-	var doneEffects []int
+	anyDone := false
 
-	a.effectsMutex.RLock()
 	// Reset array
-	colorArray := make([]colorful.Color, len(a.leds))
-	for id, effect := range a.effects {
+	clear(a.back)
+
+	heldComposed := false
+	now := time.Now()
+	a.effectsMutex.RLock()
+	for _, effect := range a.effects {
 		// Get the next values for the effect range.
 		nextValues := effect.NextValues()
+		layer := effect.GetLayer()
+		if !heldComposed && layer.Layer >= HELD_LAYER {
+			a.composeHeld()
+			heldComposed = true
+		}
 
-		// Apply the next values to the LEDs.
+		level := 1.0
+		if fade, ok := a.fades[effect]; ok {
+			level = fade.level(now)
+			if fade.done(now) {
+				effect.SetDone()
+			}
+		}
+
+		// Blend the next values over the layers below, a fading effect partially.
 		for i, ledNumber := range effect.GetRange() {
-			next := nextValues[i]
-			if !next.AlmostEqualRgb(colorful.Color{}) {
-				// Only modify the color if it is not black.
-				colorArray[ledNumber] = next
-				// TODO: Priority based update (priority per effect)
+			if level == 0 {
+				break
+			}
+			if ledNumber < 0 || ledNumber >= len(a.back) {
+				continue
 			}
+			dst := a.back[ledNumber]
+			composed := layer.Compose(dst, nextValues[i])
+			if level < 1 {
+				composed = colorful.Color{
+					R: dst.R + (composed.R-dst.R)*level,
+					G: dst.G + (composed.G-dst.G)*level,
+					B: dst.B + (composed.B-dst.B)*level,
+				}
+			}
+			a.back[ledNumber] = composed
 		}
 
 		// Check if the effect is finished to delete it later.
 		if effect.IsDone() {
-			doneEffects = append(doneEffects, id)
+			anyDone = true
 		}
 	}
 	a.effectsMutex.RUnlock()
-
-	for i := range a.leds {
-		a.leds[i].Lock()
-		a.leds[i].color = colorArray[i]
-		a.leds[i].Unlock()
+	if !heldComposed {
+		a.composeHeld()
 	}
 
-	// Remove all the effects that have finished.
-	if len(doneEffects) > 0 {
+	a.applyMaster()
+
+	// Publish the frame.
+	a.frameMutex.Lock()
+	a.front, a.back = a.back, a.front
+	a.frameMutex.Unlock()
+
+	// Remove all the effects that have finished. They are matched again under the write
+	// lock, SetLEDsEffect may have inserted effects below them in the meantime.
+	if anyDone {
 		a.effectsMutex.Lock()
 		defer a.effectsMutex.Unlock()
+		a.effects = slices.DeleteFunc(a.effects, func(effect effects.Effect) bool {
+			if !effect.IsDone() {
+				return false
+			}
+			delete(a.fades, effect)
+			return true
+		})
+	}
+}
 
-		for i := len(doneEffects) - 1; i >= 0; i-- {
-			id := doneEffects[i]
-			a.effects = append(a.effects[:id], a.effects[id+1:]...)
+// composeHeld draws the held pixels over the back buffer, unset pixels are transparent.
+func (a *LEDArrayColor) composeHeld() {
+	a.heldMutex.RLock()
+	defer a.heldMutex.RUnlock()
+	for i, color := range a.held {
+		if color != (colorful.Color{}) {
+			a.back[i] = color
 		}
 	}
 }
 
+// SetLED holds an LED at the given RGB values, or releases it if on is false.
 func (a *LEDArrayColor) SetLED(ledNumber int, on bool, red, green, blue uint8) {
-	// log.Printf("Set LED %d int %d %t", ledNumber, intensity, on)
-	if on {
-		a.leds[ledNumber].Set(colorful.Color{R: float64(red), G: float64(green), B: float64(blue)})
-	} else {
-		a.leds[ledNumber].Set(colorful.Color{})
-	}
+	a.SetLEDs(ledNumber, ledNumber+1, on, red, green, blue)
 }
 
-// Set an array of consecutive leds [first:last] to the given RGB values or off if on is false.
+// SetLEDs holds the LEDs [first:last) at the given RGB values, or releases them if on is false.
+// Held LEDs stay lit on every frame until released.
 func (a *LEDArrayColor) SetLEDs(first, last int, on bool, red, green, blue uint8) {
-	// start := time.Now()
+	first, last = max(0, first), min(a.amount, last)
+	if first >= last {
+		return
+	}
+	color := colorful.Color{}
 	if on {
-		for _, led := range a.leds[first:last] {
-
-			// led.Set(colorful.LinearRgb(float64(red)/255, float64(green)/255, float64(blue)/255))
-			led.Set(colorful.Color{R: float64(red) / 255, G: float64(green) / 255, B: float64(blue) / 255})
-		}
-	} else {
-		for _, led := range a.leds[first:last] {
-			led.Set(colorful.Color{})
-		}
+		color = colorful.Color{R: float64(red) / 255, G: float64(green) / 255, B: float64(blue) / 255}
+	}
+	a.heldMutex.Lock()
+	defer a.heldMutex.Unlock()
+	for i := first; i < last; i++ {
+		a.held[i] = color
 	}
-	// log.Printf("[M]Set LEDs %d - %d %t (%s)", first, last, on, time.Since(start))
 }
 
+// SetLEDsEffect adds an effect above every effect of the same or a lower layer.
 func (a *LEDArrayColor) SetLEDsEffect(effect effects.Effect) {
 	a.effectsMutex.Lock()
 	defer a.effectsMutex.Unlock()
-	a.effects = append(a.effects, effect)
+	layer := effect.GetLayer().Layer
+	position := len(a.effects)
+	for position > 0 && a.effects[position-1].GetLayer().Layer > layer {
+		position--
+	}
+	a.effects = slices.Insert(a.effects, position, effect)
 }
 
 func NewLEDArrayColor(amount int) *LEDArrayColor {
-	array := make([]*LEDColor, amount)
-	for i := range array {
-		array[i] = &LEDColor{}
-	}
 	log.Printf("Initialized array with %d LEDs.\n", amount)
 	return &LEDArrayColor{
-		leds:         array,
-		effectsMutex: sync.RWMutex{},
+		amount: amount,
+		back:   make([]colorful.Color, amount),
+		front:  make([]colorful.Color, amount),
+		held:   make([]colorful.Color, amount),
+		fades:  make(map[effects.Effect]fade),
+		master: newMaster(),
 	}
 }
-
-type LEDColor struct {
-	sync.RWMutex // TODO: Maybe can remove
-	color        colorful.Color
-}
-
-func (l *LEDColor) Get() []byte {
-	l.RLock()
-	defer l.RUnlock()
-
-	r, g, b := colorCorrection(l.color.RGB255())
-
-	return []byte{r, g, b}
-}
-
-func (l *LEDColor) Set(color colorful.Color) {
-	l.Lock()
-	defer l.Unlock()
-	l.color = color
-}
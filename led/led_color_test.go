@@ -0,0 +1,236 @@
+package led_test
+
+import (
+	"ddp-sender/led"
+	"ddp-sender/updater/effects"
+	"ddp-sender/util"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// alternatingEffect lights its whole range with a different color on every frame.
+type alternatingEffect struct {
+	ledRange []int
+	frame    int
+	util.DoneState
+	effects.LayerOptions
+}
+
+func (e *alternatingEffect) GetRange() []int { return e.ledRange }
+
+func (e *alternatingEffect) NextValues() []colorful.Color {
+	e.frame++
+	values := make([]colorful.Color, len(e.ledRange))
+	for i := range values {
+		values[i] = colorful.Color{R: float64(e.frame%2+1) / 2}
+	}
+	return values
+}
+
+func (e *alternatingEffect) OffEvent(velocity uint8)       {}
+func (e *alternatingEffect) Retrigger(velocity uint8) bool { return true }
+
+func TestLEDArrayColor_GetArrayReturnsWholeFrames(t *testing.T) {
+	const amount = 1000
+	array := led.NewLEDArrayColor(amount)
+	array.SetLEDsEffect(&alternatingEffect{ledRange: util.MakeRange(0, amount, 1)})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			array.SetNextEffectValues()
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		frame := array.GetArray()
+		for i := 3; i < len(frame); i += 3 {
+			if frame[i] != frame[0] {
+				t.Fatalf("Torn frame: LED 0 red = %d, LED %d red = %d", frame[0], i/3, frame[i])
+			}
+		}
+	}
+}
+
+func opacity(value float64) *float64 {
+	return &value
+}
+
+func TestLEDArrayColor_ComposesLayers(t *testing.T) {
+	wash := colorful.Color{R: 0, G: 0, B: 0.4}
+	flash := colorful.Color{R: 0.6, G: 0, B: 0.4}
+
+	tests := []struct {
+		name   string
+		layer  effects.LayerOptions
+		expect colorful.Color
+	}{
+		{"Replace", effects.LayerOptions{Layer: 1}, colorful.Color{R: 0.6, G: 0, B: 0.4}},
+		{"Add", effects.LayerOptions{Layer: 1, Blend: effects.BLEND_ADD}, colorful.Color{R: 0.6, G: 0, B: 0.8}},
+		{"Max", effects.LayerOptions{Layer: 1, Blend: effects.BLEND_MAX}, colorful.Color{R: 0.6, G: 0, B: 0.4}},
+		{"Multiply", effects.LayerOptions{Layer: 1, Blend: effects.BLEND_MULTIPLY}, colorful.Color{R: 0, G: 0, B: 0.16}},
+		{"Alpha", effects.LayerOptions{Layer: 1, Blend: effects.BLEND_ALPHA, Opacity: opacity(0.5)}, colorful.Color{R: 0.3, G: 0, B: 0.4}},
+		{"Invisible", effects.LayerOptions{Layer: 1, Opacity: opacity(0)}, wash},
+		// A lower layer is composed first even when triggered later.
+		{"Lower layer", effects.LayerOptions{Layer: -1}, colorful.Color{R: 0, G: 0, B: 0.4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			array := led.NewLEDArrayColor(1)
+			array.SetLEDsEffect(&effects.Static{Range: []int{0}, Color: wash})
+			effect := &effects.Static{Range: []int{0}, Color: flash}
+			effect.SetLayer(tt.layer)
+			array.SetLEDsEffect(effect)
+
+			array.SetNextEffectValues()
+			if got := array.GetFrame(nil)[0]; !got.AlmostEqualRgb(tt.expect) {
+				t.Errorf("Composed color = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestLEDArrayColor_HeldLayer(t *testing.T) {
+	below := &effects.Static{Range: []int{0, 1, 2}, Color: colorful.Color{B: 0.4}}
+	below.SetLayer(effects.LayerOptions{Layer: led.HELD_LAYER - 1})
+	above := &effects.Static{Range: []int{2}, Color: colorful.Color{G: 0.2}}
+
+	array := led.NewLEDArrayColor(4)
+	array.SetLEDsEffect(above)
+	array.SetLEDsEffect(below)
+	array.SetLED(0, true, 255, 0, 0)
+	array.SetLEDs(1, 3, true, 0, 0, 51)
+	array.SetLED(10, true, 255, 255, 255) // Out of range, ignored.
+
+	want := []colorful.Color{{R: 1}, {B: 0.2}, {G: 0.2}, {}}
+	// Held LEDs persist across frames.
+	for frame := 0; frame < 3; frame++ {
+		array.SetNextEffectValues()
+		for i, got := range array.GetFrame(nil) {
+			if !got.AlmostEqualRgb(want[i]) {
+				t.Errorf("Frame %d LED %d = %v, want %v", frame, i, got, want[i])
+			}
+		}
+	}
+
+	// Released LEDs show the effects below again.
+	array.SetLEDs(0, 2, false, 0, 0, 0)
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[:2]; !got[0].AlmostEqualRgb(colorful.Color{B: 0.4}) || !got[1].AlmostEqualRgb(colorful.Color{B: 0.4}) {
+		t.Errorf("Released LEDs = %v, want the layer below", got)
+	}
+}
+
+func TestLEDArrayColor_FadeEffect(t *testing.T) {
+	below := &effects.Static{Range: []int{0, 1, 2}, Color: colorful.Color{B: 1}}
+	below.SetLayer(effects.LayerOptions{Layer: -1})
+	half := &effects.Static{Range: []int{0}, Color: colorful.Color{R: 1}}
+	hidden := &effects.Static{Range: []int{1}, Color: colorful.Color{R: 1}}
+	ended := &effects.Static{Range: []int{2}, Color: colorful.Color{R: 1}}
+
+	array := led.NewLEDArrayColor(3)
+	for _, effect := range []effects.Effect{below, half, hidden, ended} {
+		array.SetLEDsEffect(effect)
+	}
+	array.FadeEffect(half, 0.5, 1, time.Hour, time.Hour)
+	array.FadeEffect(hidden, 0, 1, time.Hour, time.Hour)
+	array.FadeEffect(ended, 1, 0, 0, 0)
+
+	// Fading effects let the layers below show through, faded out ones end.
+	want := []colorful.Color{{R: 0.5, B: 0.5}, {B: 1}, {B: 1}}
+	for frame := 0; frame < 2; frame++ {
+		array.SetNextEffectValues()
+		for i, got := range array.GetFrame(nil) {
+			if !got.AlmostEqualRgb(want[i]) {
+				t.Errorf("Frame %d LED %d = %v, want %v", frame, i, got, want[i])
+			}
+		}
+	}
+	if !ended.IsDone() {
+		t.Error("Faded out effect is not done")
+	}
+
+	// Removing the faded out effect keeps the fades of the others.
+	array.SetLEDsEffect(&effects.Static{Range: []int{2}, Color: colorful.Color{G: 1}})
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[0]; !got.AlmostEqualRgb(want[0]) {
+		t.Errorf("LED 0 = %v, want %v", got, want[0])
+	}
+}
+
+func TestLEDArrayColor_RemovesDoneEffectsWhileInserting(t *testing.T) {
+	const amount = 200
+	array := led.NewLEDArrayColor(amount)
+
+	// Live effects are inserted below ending ones while frames are rendered.
+	inserted := make(chan struct{})
+	go func() {
+		defer close(inserted)
+		for i := 0; i < amount; i++ {
+			live := &effects.Static{Range: []int{i}, Color: colorful.Color{R: 1}}
+			live.SetLayer(effects.LayerOptions{Layer: -1})
+			array.SetLEDsEffect(live)
+		}
+	}()
+	for done := false; !done; {
+		select {
+		case <-inserted:
+			done = true
+		default:
+		}
+		ending := &effects.Static{Range: []int{0}, Color: colorful.Color{G: 1}}
+		ending.SetDone()
+		array.SetLEDsEffect(ending)
+		array.SetNextEffectValues()
+	}
+
+	array.SetNextEffectValues()
+	for i, got := range array.GetFrame(nil) {
+		if !got.AlmostEqualRgb(colorful.Color{R: 1}) {
+			t.Fatalf("LED %d = %v, want its live effect", i, got)
+		}
+	}
+}
+
+func newBenchmarkArray(amount int) *led.LEDArrayColor {
+	array := led.NewLEDArrayColor(amount)
+	array.SetLEDsEffect(effects.NewStatic(util.MakeRange(0, amount, 1), colorful.Color{R: 1, G: 0.5, B: 0.25}, 127))
+	return array
+}
+
+func BenchmarkLEDArrayColor_SetNextEffectValues(b *testing.B) {
+	for _, amount := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("%d", amount), func(b *testing.B) {
+			array := newBenchmarkArray(amount)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				array.SetNextEffectValues()
+			}
+		})
+	}
+}
+
+func BenchmarkLEDArrayColor_GetArray(b *testing.B) {
+	for _, amount := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("%d", amount), func(b *testing.B) {
+			array := newBenchmarkArray(amount)
+			array.SetNextEffectValues()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				array.GetArray()
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package led
+
+import (
+	"slices"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// MasterControl adjusts every composed frame, whatever set the LEDs.
+type MasterControl interface {
+	SetBrightness(brightness float64)
+	// SetBlackout fades to (on) or back from black over fade, 0 switches instantly.
+	SetBlackout(on bool, fade time.Duration)
+	// SetFreeze holds the current frame while effects keep running underneath.
+	SetFreeze(on bool)
+	MasterState() MasterState
+}
+
+// MasterState is a snapshot of the master controls.
+type MasterState struct {
+	Brightness float64 `json:"brightness"`
+	Blackout   bool    `json:"blackout"` // Blackout requested, the fade may still be running.
+	Level      float64 `json:"level"`    // Current blackout fade level, 0 is black.
+	Frozen     bool    `json:"frozen"`
+}
+
+type master struct {
+	brightness   float64
+	blackout     bool
+	fadeFrom     float64
+	fadeTo       float64
+	fadeStart    time.Time
+	fadeDuration time.Duration
+	frozen       bool
+	held         []colorful.Color // Composed frame shown while frozen.
+}
+
+func newMaster() master {
+	return master{brightness: 1, fadeFrom: 1, fadeTo: 1}
+}
+
+// level returns the blackout fade level at now.
+func (m *master) level(now time.Time) float64 {
+	elapsed := now.Sub(m.fadeStart)
+	if m.fadeDuration <= 0 || elapsed >= m.fadeDuration {
+		return m.fadeTo
+	}
+	return m.fadeFrom + (m.fadeTo-m.fadeFrom)*float64(elapsed)/float64(m.fadeDuration)
+}
+
+func (a *LEDArrayColor) SetBrightness(brightness float64) {
+	a.masterMutex.Lock()
+	defer a.masterMutex.Unlock()
+	a.master.brightness = max(0, min(1, brightness))
+}
+
+func (a *LEDArrayColor) SetBlackout(on bool, fade time.Duration) {
+	a.masterMutex.Lock()
+	defer a.masterMutex.Unlock()
+	now := time.Now()
+	// Start from the current level so reversing a running fade does not jump.
+	a.master.fadeFrom = a.master.level(now)
+	a.master.fadeTo = 1
+	if on {
+		a.master.fadeTo = 0
+	}
+	a.master.fadeStart = now
+	a.master.fadeDuration = fade
+	a.master.blackout = on
+}
+
+func (a *LEDArrayColor) SetFreeze(on bool) {
+	a.masterMutex.Lock()
+	defer a.masterMutex.Unlock()
+	if !on {
+		a.master.held = nil
+	}
+	a.master.frozen = on
+}
+
+func (a *LEDArrayColor) MasterState() MasterState {
+	a.masterMutex.Lock()
+	defer a.masterMutex.Unlock()
+	return MasterState{
+		Brightness: a.master.brightness,
+		Blackout:   a.master.blackout,
+		Level:      a.master.level(time.Now()),
+		Frozen:     a.master.frozen,
+	}
+}
+
+// applyMaster swaps the composed back buffer for the held frame while frozen,
+// then scales it by the brightness and blackout level.
+func (a *LEDArrayColor) applyMaster() {
+	a.masterMutex.Lock()
+	defer a.masterMutex.Unlock()
+	if a.master.frozen {
+		if a.master.held == nil {
+			a.master.held = slices.Clone(a.back)
+		}
+		copy(a.back, a.master.held)
+	}
+
+	level := a.master.brightness * a.master.level(time.Now())
+	if level == 1 {
+		return
+	}
+	for i, color := range a.back {
+		a.back[i] = colorful.Color{R: color.R * level, G: color.G * level, B: color.B * level}
+	}
+}
@@ -0,0 +1,79 @@
+package led_test
+
+import (
+	"ddp-sender/led"
+	"ddp-sender/updater/effects"
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestLEDArrayColor_MasterBrightnessAndBlackout(t *testing.T) {
+	array := led.NewLEDArrayColor(1)
+	array.SetLEDsEffect(&effects.Static{Range: []int{0}, Color: colorful.Color{R: 1, G: 0.5}})
+
+	array.SetBrightness(0.5)
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[0]; !got.AlmostEqualRgb(colorful.Color{R: 0.5, G: 0.25}) {
+		t.Errorf("Frame at half brightness = %v, want {0.5 0.25 0}", got)
+	}
+
+	array.SetBlackout(true, 0)
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[0]; got != (colorful.Color{}) {
+		t.Errorf("Frame during blackout = %v, want black", got)
+	}
+
+	array.SetBlackout(false, 0)
+	array.SetBrightness(1)
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[0]; !got.AlmostEqualRgb(colorful.Color{R: 1, G: 0.5}) {
+		t.Errorf("Frame after blackout = %v, want the composed color", got)
+	}
+}
+
+func TestLEDArrayColor_MasterFadedBlackout(t *testing.T) {
+	array := led.NewLEDArrayColor(1)
+	array.SetLEDsEffect(&effects.Static{Range: []int{0}, Color: colorful.Color{R: 1}})
+
+	array.SetBlackout(true, 200*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[0].R; got <= 0 || got >= 1 {
+		t.Errorf("Red during the fade = %g, want between 0 and 1", got)
+	}
+	if state := array.MasterState(); !state.Blackout || state.Level <= 0 || state.Level >= 1 {
+		t.Errorf("MasterState() = %+v, want a running blackout fade", state)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[0]; got != (colorful.Color{}) {
+		t.Errorf("Frame after the fade = %v, want black", got)
+	}
+}
+
+func TestLEDArrayColor_MasterFreeze(t *testing.T) {
+	array := led.NewLEDArrayColor(1)
+	array.SetLEDsEffect(&alternatingEffect{ledRange: []int{0}})
+
+	array.SetNextEffectValues()
+	array.SetFreeze(true)
+	array.SetNextEffectValues()
+	held := array.GetFrame(nil)[0]
+	for i := 0; i < 3; i++ {
+		array.SetNextEffectValues()
+		if got := array.GetFrame(nil)[0]; got != held {
+			t.Fatalf("Frame while frozen = %v, want held %v", got, held)
+		}
+	}
+
+	array.SetFreeze(false)
+	array.SetNextEffectValues()
+	next := array.GetFrame(nil)[0]
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[0]; got == next {
+		t.Errorf("Frames after unfreeze = %v twice, want the effect to run again", got)
+	}
+}
@@ -1,32 +1,68 @@
 package listener
 
 import (
+	"context"
+	"ddp-sender/util"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 )
 
 type HTTPMidiReceiver struct {
 	SendChannel chan MidiMessage
+	port        int
 }
 
+// ReceiveMidi reads a JSON MidiMessage, or raw MIDI 1.0 bytes when the body is application/octet-stream.
+// Messages are sent until the request context is done, which RunListener derives from its own.
 func (r HTTPMidiReceiver) ReceiveMidi(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Content-Type") == "application/octet-stream" {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		var parser Parser
+		r.send(req.Context(), parser.Parse(nil, data)...)
+		return
+	}
+
 	var message MidiMessage
 	err := json.NewDecoder(req.Body).Decode(&message)
 	if err != nil {
 		log.Println(err)
 	}
 	// log.Printf("Received note %d velocity %d (%t)\n", message.Note, message.Velocity, message.On)
-	r.SendChannel <- message
+	r.send(req.Context(), message)
+}
+
+// send hands messages to the updater, giving up once ctx is done.
+func (r HTTPMidiReceiver) send(ctx context.Context, messages ...MidiMessage) {
+	for _, message := range messages {
+		select {
+		case r.SendChannel <- message:
+		case <-ctx.Done():
+			return
+		}
+	}
 }
 
-func (r HTTPMidiReceiver) RunListener() error {
-	http.HandleFunc("/midi", r.ReceiveMidi)
-	return http.ListenAndServe(":8090", nil)
+func (r HTTPMidiReceiver) RunListener(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/midi", r.ReceiveMidi)
+	return util.ServeHTTP(ctx, &http.Server{
+		Addr:        fmt.Sprintf(":%d", r.port),
+		Handler:     mux,
+		BaseContext: func(net.Listener) context.Context { return ctx }, // Pending sends end on shutdown.
+	})
 }
 
-func NewHTTPMidiReceiver() *HTTPMidiReceiver {
+func NewHTTPMidiReceiver(port int) *HTTPMidiReceiver {
 	return &HTTPMidiReceiver{
 		SendChannel: make(chan MidiMessage, 255),
+		port:        port,
 	}
 }
@@ -2,8 +2,11 @@ package listener_test
 
 import (
 	"bytes"
+	"context"
 	"ddp-sender/listener"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,7 +15,7 @@ import (
 
 func TestHTTPMidiReceiver_RunListener(t *testing.T) {
 	// Create a new HTTPMidiReceiver
-	receiver := listener.NewHTTPMidiReceiver()
+	receiver := listener.NewHTTPMidiReceiver(8090)
 
 	// Start the HTTP server in a test server
 	server := httptest.NewServer(http.HandlerFunc(receiver.ReceiveMidi))
@@ -29,7 +32,7 @@ func TestHTTPMidiReceiver_RunListener(t *testing.T) {
 
 	// Start the RunListener in a goroutine
 	go func() {
-		err := receiver.RunListener()
+		err := receiver.RunListener(context.Background())
 		if err != nil {
 			t.Errorf("RunListener failed: %v", err)
 		}
@@ -64,3 +67,36 @@ func TestHTTPMidiReceiver_RunListener(t *testing.T) {
 		t.Fatalf("Received message is not correct: %v", receivedMessage)
 	}
 }
+
+func TestHTTPMidiReceiver_StopsWhileSending(t *testing.T) {
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on TCP: %v", err)
+	}
+	port := probe.Addr().(*net.TCPAddr).Port
+	probe.Close()
+
+	// Nobody reads the messages, as after the updater has stopped.
+	receiver := listener.NewHTTPMidiReceiver(port)
+	receiver.SendChannel = make(chan listener.MidiMessage)
+	ctx, cancel := context.WithCancel(context.Background())
+	go receiver.RunListener(ctx)
+	time.Sleep(100 * time.Millisecond) // Wait for the server to start.
+
+	responded := make(chan error, 1)
+	go func() {
+		client := &http.Client{Timeout: 2 * time.Second}
+		resp, err := client.Post(fmt.Sprintf("http://127.0.0.1:%d/midi", port), "application/json", bytes.NewBufferString(`{"note": 50, "velocity": 127, "on": true, "channel": 1}`))
+		if err == nil {
+			resp.Body.Close()
+		}
+		responded <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Shutting down ends the pending send and lets the request complete.
+	cancel()
+	if err := <-responded; err != nil {
+		t.Errorf("Request error = %v, want a response", err)
+	}
+}
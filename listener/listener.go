@@ -1,12 +1,70 @@
 package listener
 
+import (
+	"context"
+	"fmt"
+)
+
 type MidiReceiver interface {
-	RunListener() error
+	// RunListener receives messages until ctx is cancelled.
+	RunListener(ctx context.Context) error
+}
+
+// MessageKind is the type of a MIDI message, the zero value is a note so messages
+// written before kinds existed stay notes.
+type MessageKind uint8
+
+const (
+	KIND_NOTE               MessageKind = iota // Note on, or off when On is false.
+	KIND_POLY_AFTERTOUCH                       // Pressure (Value) of a held Note.
+	KIND_CONTROL_CHANGE                        // Controller set to Value.
+	KIND_PROGRAM_CHANGE                        // Program Value selected.
+	KIND_CHANNEL_AFTERTOUCH                    // Pressure (Value) of the whole channel.
+	KIND_PITCH_BEND                            // Bend from -8192 to 8191.
+	KIND_CLOCK                                 // 24 clocks per quarter note, no channel.
+	KIND_START                                 // Transport start, no channel.
+	KIND_CONTINUE                              // Transport continue, no channel.
+	KIND_STOP                                  // Transport stop, no channel.
+)
+
+var kindNames = []string{"note", "poly_aftertouch", "control_change", "program_change", "channel_aftertouch", "pitch_bend", "clock", "start", "continue", "stop"}
+
+func (k MessageKind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", k)
+}
+
+func (k MessageKind) MarshalText() ([]byte, error) {
+	if int(k) >= len(kindNames) {
+		return nil, fmt.Errorf("unknown message kind %d", k)
+	}
+	return []byte(kindNames[k]), nil
+}
+
+func (k *MessageKind) UnmarshalText(text []byte) error {
+	for i, name := range kindNames {
+		if name == string(text) {
+			*k = MessageKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown message kind %q", text)
+}
+
+// IsRealtime reports whether the kind is a system real-time message, which has no channel.
+func (k MessageKind) IsRealtime() bool {
+	return k >= KIND_CLOCK
 }
 
 type MidiMessage struct {
-	Note     uint8 `json:"note"`
-	Velocity uint8 `json:"velocity"`
-	On       bool  `json:"on"`
-	Channel  uint8 `json:"channel"`
+	Kind       MessageKind `json:"kind,omitempty"` // Omitted for notes.
+	Note       uint8       `json:"note"`           // Note and poly aftertouch.
+	Velocity   uint8       `json:"velocity"`       // Note velocity.
+	On         bool        `json:"on"`
+	Channel    uint8       `json:"channel"`              // 1-16, 0 for real-time messages.
+	Controller uint8       `json:"controller,omitempty"` // Control change number.
+	Value      uint8       `json:"value,omitempty"`      // Control change value, aftertouch pressure or program number.
+	Bend       int16       `json:"bend,omitempty"`       // Pitch bend, 0 is centered.
 }
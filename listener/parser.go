@@ -0,0 +1,86 @@
+package listener
+
+// Parser converts a MIDI 1.0 byte stream into messages. It follows running status,
+// handles real-time messages interleaved anywhere (even inside other messages) and
+// skips SysEx and system common messages. The zero value is ready to use, a Parser
+// keeps state between calls so a stream can be fed in arbitrary chunks.
+type Parser struct {
+	status byte // Status of the message being read, 0 when waiting for a status byte.
+	data   [2]byte
+	count  int  // Data bytes read for status.
+	sysex  bool // Inside a SysEx message, data bytes are skipped.
+}
+
+// Parse appends the messages completed by data to dst.
+func (p *Parser) Parse(dst []MidiMessage, data []byte) []MidiMessage {
+	for _, b := range data {
+		if message, ok := p.Feed(b); ok {
+			dst = append(dst, message)
+		}
+	}
+	return dst
+}
+
+// Feed reads one byte and returns the message it completes, if any.
+func (p *Parser) Feed(b byte) (MidiMessage, bool) {
+	switch {
+	case b >= 0xF8:
+		// Real-time messages do not interrupt the message or SysEx being read.
+		switch b {
+		case STATUS_CLOCK, STATUS_START, STATUS_CONTINUE, STATUS_STOP:
+			return decodeMIDI(b, nil), true
+		case STATUS_RESET:
+			p.Reset()
+		}
+		return MidiMessage{}, false
+	case b == STATUS_SYSEX:
+		p.sysex, p.status, p.count = true, 0, 0
+		return MidiMessage{}, false
+	case b == STATUS_END_OF_SYSEX:
+		p.sysex = false
+		return MidiMessage{}, false
+	case b >= 0x80:
+		// Any other status byte ends a SysEx and cancels an incomplete message.
+		p.sysex, p.status, p.count = false, b, 0
+		if b >= 0xF0 && systemCommonLength(b) == 0 {
+			// Tune request and undefined system common messages have no data.
+			p.status = 0
+		}
+		return MidiMessage{}, false
+	}
+
+	if p.sysex || p.status == 0 {
+		return MidiMessage{}, false
+	}
+	p.data[p.count] = b
+	p.count++
+	if p.status >= 0xF0 {
+		// System common messages are skipped and cancel running status.
+		if p.count == systemCommonLength(p.status) {
+			p.status, p.count = 0, 0
+		}
+		return MidiMessage{}, false
+	}
+	if p.count < dataLength(p.status) {
+		return MidiMessage{}, false
+	}
+	// Keep the status for running status.
+	p.count = 0
+	return decodeMIDI(p.status, p.data[:]), true
+}
+
+// Reset forgets the running status and any partial message.
+func (p *Parser) Reset() {
+	*p = Parser{}
+}
+
+// systemCommonLength returns the amount of data bytes of a system common status byte.
+func systemCommonLength(status byte) int {
+	switch status {
+	case 0xF1, 0xF3: // MTC quarter frame, song select.
+		return 1
+	case 0xF2: // Song position pointer.
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package listener_test
+
+import (
+	"ddp-sender/listener"
+	"reflect"
+	"testing"
+)
+
+func TestParser_Parse(t *testing.T) {
+	noteOn := func(note, velocity uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: 1, Note: note, Velocity: velocity, On: velocity > 0}
+	}
+	clock := listener.MidiMessage{Kind: listener.KIND_CLOCK}
+
+	tests := []struct {
+		name string
+		data []byte
+		want []listener.MidiMessage
+	}{
+		{
+			name: "Running status",
+			data: []byte{0x90, 60, 100, 62, 90, 60, 0},
+			want: []listener.MidiMessage{noteOn(60, 100), noteOn(62, 90), noteOn(60, 0)},
+		},
+		{
+			name: "Real-time inside a message",
+			data: []byte{0x90, 60, 0xF8, 100, 0xFE, 62, 0xF8, 90},
+			want: []listener.MidiMessage{clock, noteOn(60, 100), clock, noteOn(62, 90)},
+		},
+		{
+			name: "SysEx skipped, running status cancelled",
+			data: []byte{0x90, 60, 100, 0xF0, 0x7E, 0x01, 0xF8, 0x02, 0xF7, 62, 90, 0xB1, 7, 64},
+			want: []listener.MidiMessage{noteOn(60, 100), clock, {Kind: listener.KIND_CONTROL_CHANGE, Channel: 2, Controller: 7, Value: 64}},
+		},
+		{
+			name: "SysEx ended by a status byte",
+			data: []byte{0xF0, 0x43, 0x10, 0xC4, 5},
+			want: []listener.MidiMessage{{Kind: listener.KIND_PROGRAM_CHANGE, Channel: 5, Value: 5}},
+		},
+		{
+			name: "System common skipped",
+			data: []byte{0xF2, 0x10, 0x20, 0xF3, 1, 0xF6, 0xE0, 0x00, 0x40},
+			want: []listener.MidiMessage{{Kind: listener.KIND_PITCH_BEND, Channel: 1}},
+		},
+		{
+			name: "Incomplete message cancelled",
+			data: []byte{0x90, 60, 0xD0, 20},
+			want: []listener.MidiMessage{{Kind: listener.KIND_CHANNEL_AFTERTOUCH, Channel: 1, Value: 20}},
+		},
+		{
+			name: "Data without status ignored",
+			data: []byte{60, 100, 0xFC},
+			want: []listener.MidiMessage{{Kind: listener.KIND_STOP}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parser listener.Parser
+			got := parser.Parse(nil, tt.data)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+
+			// Feeding the stream byte by byte gives the same messages.
+			parser.Reset()
+			var split []listener.MidiMessage
+			for _, b := range tt.data {
+				split = parser.Parse(split, []byte{b})
+			}
+			if !reflect.DeepEqual(split, tt.want) {
+				t.Errorf("Parse() byte by byte = %+v, want %+v", split, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParser(f *testing.F) {
+	f.Add([]byte{0x90, 60, 100, 62, 90})
+	f.Add([]byte{0xF0, 0x7E, 0xF8, 0xF7, 0xB0, 7, 0xF8, 127})
+	f.Add([]byte{0xE5, 0x7F, 0x7F, 0xC0, 1, 2, 0xF2, 1})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var parser listener.Parser
+		messages := parser.Parse(nil, data)
+
+		var encoded []byte
+		for _, message := range messages {
+			if message.Kind.IsRealtime() {
+				if message.Channel != 0 {
+					t.Fatalf("Real-time message %+v has a channel", message)
+				}
+			} else if message.Channel < 1 || message.Channel > 16 {
+				t.Fatalf("Message %+v has channel %d", message, message.Channel)
+			}
+			if message.Note > 127 || message.Velocity > 127 || message.Controller > 127 || message.Value > 127 {
+				t.Fatalf("Message %+v has a data byte above 127", message)
+			}
+			if message.Bend < -8192 || message.Bend > 8191 {
+				t.Fatalf("Message %+v has bend out of range", message)
+			}
+			encoded = listener.AppendMIDI(encoded, message)
+		}
+
+		// Parsed messages survive encoding and parsing again.
+		var again listener.Parser
+		if reparsed := again.Parse(nil, encoded); !reflect.DeepEqual(reparsed, messages) {
+			t.Fatalf("Parse(AppendMIDI()) = %+v, want %+v", reparsed, messages)
+		}
+	})
+}
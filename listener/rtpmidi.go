@@ -0,0 +1,344 @@
+package listener
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// AppleMIDI session commands, sent on both ports with the 0xFFFF signature.
+const (
+	APPLEMIDI_INVITATION = "IN"
+	APPLEMIDI_ACCEPT     = "OK"
+	APPLEMIDI_REJECT     = "NO"
+	APPLEMIDI_END        = "BY"
+	APPLEMIDI_SYNC       = "CK"
+	APPLEMIDI_FEEDBACK   = "RS"
+)
+
+const (
+	appleMIDIVersion = 2
+	rtpPayloadType   = 0x61
+	feedbackInterval = time.Second // Receiver feedback lets the sender trim its recovery journal.
+	maxReadBackoff   = time.Second // Longest wait after repeated read errors.
+)
+
+// RTPMidiReceiver is an AppleMIDI (RTP-MIDI) session responder. A DAW invites it on the
+// control port and on the data port (control port + 1), syncs clocks and sends RTP-MIDI
+// packets on the data port. Recovery journals are skipped, lost packets are not recovered.
+type RTPMidiReceiver struct {
+	SendChannel chan MidiMessage
+	Name        string // Session name shown by the initiator.
+	port        int
+	ssrc        uint32
+	start       time.Time
+
+	sessionsMutex sync.Mutex
+	sessions      map[uint32]*rtpSession // By initiator SSRC.
+}
+
+type rtpSession struct {
+	name         string
+	token        uint32
+	controlAddr  *net.UDPAddr
+	dataAddr     *net.UDPAddr // Nil until the data port invitation.
+	lastFeedback time.Time
+}
+
+func (r *RTPMidiReceiver) RunListener(ctx context.Context) error {
+	control, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: r.port})
+	if err != nil {
+		return err
+	}
+	data, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: r.port + 1})
+	if err != nil {
+		control.Close()
+		return err
+	}
+	r.start = time.Now()
+
+	// Closing the sockets unblocks the pending reads.
+	stop := context.AfterFunc(ctx, func() {
+		r.endSessions(control)
+		control.Close()
+		data.Close()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		r.serve(ctx, control, control, false)
+	}()
+	go func() {
+		defer wg.Done()
+		r.serve(ctx, data, control, true)
+	}()
+	wg.Wait()
+	return nil
+}
+
+// serve reads the packets of one port until it is closed. Other read errors are retried
+// after a wait doubling up to maxReadBackoff.
+func (r *RTPMidiReceiver) serve(ctx context.Context, conn, control *net.UDPConn, dataPort bool) {
+	var buf [1500]byte
+	var backoff time.Duration
+	for {
+		n, addr, err := conn.ReadFromUDP(buf[:])
+		if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			backoff = min(max(2*backoff, 10*time.Millisecond), maxReadBackoff)
+			log.Printf("RTP-MIDI: %v, retrying in %s\n", err, backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		backoff = 0
+		packet := buf[:n]
+		if len(packet) >= 4 && packet[0] == 0xFF && packet[1] == 0xFF {
+			r.handleCommand(conn, addr, packet, dataPort)
+		} else if dataPort {
+			r.handleRTP(ctx, control, packet)
+		}
+	}
+}
+
+func (r *RTPMidiReceiver) handleCommand(conn *net.UDPConn, addr *net.UDPAddr, packet []byte, dataPort bool) {
+	command := string(packet[2:4])
+	switch command {
+	case APPLEMIDI_INVITATION:
+		if len(packet) < 16 {
+			return
+		}
+		token := binary.BigEndian.Uint32(packet[8:12])
+		ssrc := binary.BigEndian.Uint32(packet[12:16])
+		name := cString(packet[16:])
+
+		r.sessionsMutex.Lock()
+		session, ok := r.sessions[ssrc]
+		if !ok && dataPort {
+			r.sessionsMutex.Unlock()
+			// The control port invitation comes first.
+			conn.WriteToUDP(r.sessionPacket(APPLEMIDI_REJECT, token), addr)
+			return
+		}
+		if !ok {
+			session = &rtpSession{}
+			r.sessions[ssrc] = session
+		}
+		session.name, session.token = name, token
+		if dataPort {
+			session.dataAddr = addr
+			log.Printf("RTP-MIDI session with %s (%s) established\n", name, addr)
+		} else {
+			session.controlAddr = addr
+		}
+		r.sessionsMutex.Unlock()
+		conn.WriteToUDP(r.sessionPacket(APPLEMIDI_ACCEPT, token), addr)
+	case APPLEMIDI_END:
+		if len(packet) < 16 {
+			return
+		}
+		ssrc := binary.BigEndian.Uint32(packet[12:16])
+		r.sessionsMutex.Lock()
+		if session, ok := r.sessions[ssrc]; ok {
+			log.Printf("RTP-MIDI session with %s ended\n", session.name)
+			delete(r.sessions, ssrc)
+		}
+		r.sessionsMutex.Unlock()
+	case APPLEMIDI_SYNC:
+		if len(packet) < 36 {
+			return
+		}
+		// Answer count 0 with our timestamp, count 2 completes the initiator measurement.
+		if packet[8] == 0 {
+			reply := make([]byte, 36)
+			copy(reply, packet)
+			binary.BigEndian.PutUint32(reply[4:8], r.ssrc)
+			reply[8] = 1
+			binary.BigEndian.PutUint64(reply[20:28], r.timestamp())
+			conn.WriteToUDP(reply, addr)
+		}
+	}
+}
+
+// handleRTP sends the MIDI list of an RTP-MIDI packet from a known session.
+func (r *RTPMidiReceiver) handleRTP(ctx context.Context, control *net.UDPConn, packet []byte) {
+	if len(packet) < 13 || packet[0]>>6 != 2 || packet[1]&0x7F != rtpPayloadType {
+		return
+	}
+	sequence := binary.BigEndian.Uint16(packet[2:4])
+	ssrc := binary.BigEndian.Uint32(packet[8:12])
+	header := 12 + 4*int(packet[0]&0x0F)
+	if len(packet) <= header {
+		return
+	}
+
+	r.sessionsMutex.Lock()
+	session, ok := r.sessions[ssrc]
+	if !ok || session.dataAddr == nil {
+		r.sessionsMutex.Unlock()
+		return
+	}
+	messages := parseMIDIList(packet[header:])
+	if time.Since(session.lastFeedback) >= feedbackInterval && session.controlAddr != nil {
+		session.lastFeedback = time.Now()
+		feedback := make([]byte, 12)
+		copy(feedback, []byte{0xFF, 0xFF, APPLEMIDI_FEEDBACK[0], APPLEMIDI_FEEDBACK[1]})
+		binary.BigEndian.PutUint32(feedback[4:8], r.ssrc)
+		binary.BigEndian.PutUint16(feedback[8:10], sequence)
+		control.WriteToUDP(feedback, session.controlAddr)
+	}
+	r.sessionsMutex.Unlock()
+
+	for _, message := range messages {
+		select {
+		case r.SendChannel <- message:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// parseMIDIList returns the messages of the MIDI command section of an RTP-MIDI payload.
+// Delta times are ignored, the recovery journal after the list is skipped. Running status
+// only spans one packet, the first channel message of a list carries its status byte.
+func parseMIDIList(payload []byte) []MidiMessage {
+	flags := payload[0]
+	length, list := int(flags&0x0F), payload[1:]
+	if flags&0x80 != 0 {
+		if len(list) == 0 {
+			return nil
+		}
+		length, list = length<<8|int(list[0]), list[1:]
+	}
+	if length > len(list) {
+		// Truncated, keep what is there.
+		length = len(list)
+	}
+	list = list[:length]
+
+	var messages []MidiMessage
+	var running byte
+	first := flags&0x20 == 0 // Without Z the first command has no delta time.
+	for i := 0; i < len(list); first = false {
+		if !first {
+			// Delta time, up to 4 bytes with the high bit set on all but the last.
+			for end := i + 4; i < len(list) && i < end-1 && list[i]&0x80 != 0; i++ {
+			}
+			i++
+		}
+		if i >= len(list) {
+			break
+		}
+
+		b := list[i]
+		switch {
+		case b >= 0xF8:
+			if dataLength(b) == 0 {
+				messages = append(messages, decodeMIDI(b, nil))
+			}
+			i++
+		case b == STATUS_SYSEX || b == STATUS_END_OF_SYSEX || b == 0xF4:
+			// SysEx segment: F0 or F7 (continuation) up to F7, F0 (more to follow) or F4 (cancelled).
+			for i++; i < len(list) && list[i] != STATUS_END_OF_SYSEX && list[i] != STATUS_SYSEX && list[i] != 0xF4; i++ {
+			}
+			i++
+			running = 0
+		case b >= 0xF0:
+			i += 1 + systemCommonLength(b)
+			running = 0
+		default:
+			status := running
+			if b >= 0x80 {
+				status, running = b, b
+				i++
+			}
+			if status == 0 {
+				// Data without status, nothing to decode this packet.
+				return messages
+			}
+			length := dataLength(status)
+			if i+length > len(list) {
+				return messages
+			}
+			messages = append(messages, decodeMIDI(status, list[i:i+length]))
+			i += length
+		}
+	}
+	return messages
+}
+
+// sessionPacket builds an IN/OK/NO/BY packet.
+func (r *RTPMidiReceiver) sessionPacket(command string, token uint32) []byte {
+	packet := make([]byte, 16, 17+len(r.Name))
+	copy(packet, []byte{0xFF, 0xFF, command[0], command[1]})
+	binary.BigEndian.PutUint32(packet[4:8], appleMIDIVersion)
+	binary.BigEndian.PutUint32(packet[8:12], token)
+	binary.BigEndian.PutUint32(packet[12:16], r.ssrc)
+	if command == APPLEMIDI_ACCEPT {
+		packet = append(append(packet, r.Name...), 0)
+	}
+	return packet
+}
+
+// endSessions tells every initiator the sessions are over.
+func (r *RTPMidiReceiver) endSessions(control *net.UDPConn) {
+	r.sessionsMutex.Lock()
+	defer r.sessionsMutex.Unlock()
+	for ssrc, session := range r.sessions {
+		if session.controlAddr != nil {
+			control.WriteToUDP(r.sessionPacket(APPLEMIDI_END, session.token), session.controlAddr)
+		}
+		delete(r.sessions, ssrc)
+	}
+}
+
+// timestamp returns the session clock in 100 microsecond units.
+func (r *RTPMidiReceiver) timestamp() uint64 {
+	return uint64(time.Since(r.start) / (100 * time.Microsecond))
+}
+
+// Sessions returns the names of the established sessions.
+func (r *RTPMidiReceiver) Sessions() []string {
+	r.sessionsMutex.Lock()
+	defer r.sessionsMutex.Unlock()
+	var names []string
+	for _, session := range r.sessions {
+		if session.dataAddr != nil {
+			names = append(names, session.name)
+		}
+	}
+	return names
+}
+
+func cString(data []byte) string {
+	for i, b := range data {
+		if b == 0 {
+			return string(data[:i])
+		}
+	}
+	return string(data)
+}
+
+// NewRTPMidiReceiver listens on port (control) and port+1 (data), sending to sendChannel.
+func NewRTPMidiReceiver(port int, name string, sendChannel chan MidiMessage) *RTPMidiReceiver {
+	return &RTPMidiReceiver{
+		SendChannel: sendChannel,
+		Name:        name,
+		port:        port,
+		ssrc:        rand.Uint32(),
+		sessions:    make(map[uint32]*rtpSession),
+	}
+}
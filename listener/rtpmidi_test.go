@@ -0,0 +1,183 @@
+package listener_test
+
+import (
+	"bytes"
+	"context"
+	"ddp-sender/listener"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+const initiatorSSRC = 0x01020304
+
+// The tests play the initiator (DAW) side of an AppleMIDI session.
+
+func sessionPacket(command string, token uint32, name string) []byte {
+	packet := make([]byte, 16)
+	copy(packet, []byte{0xFF, 0xFF, command[0], command[1]})
+	binary.BigEndian.PutUint32(packet[4:8], 2)
+	binary.BigEndian.PutUint32(packet[8:12], token)
+	binary.BigEndian.PutUint32(packet[12:16], initiatorSSRC)
+	return append(append(packet, name...), 0)
+}
+
+// exchange sends packet until a want reply arrives, the listener may not be bound yet.
+// Replies to earlier retries are skipped.
+func exchange(t *testing.T, conn *net.UDPConn, packet []byte, want string) []byte {
+	t.Helper()
+	buf := make([]byte, 1500)
+	for start := time.Now(); time.Since(start) < 2*time.Second; {
+		conn.Write(packet)
+		conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break
+			}
+			if n >= 4 && string(buf[2:4]) == want {
+				return buf[:n]
+			}
+		}
+	}
+	t.Fatalf("No %q reply to %q", want, packet[2:4])
+	return nil
+}
+
+// readCommand returns the next want packet, skipping replies to earlier retries.
+func readCommand(t *testing.T, conn *net.UDPConn, want string) []byte {
+	t.Helper()
+	buf := make([]byte, 1500)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("No %q packet: %v", want, err)
+		}
+		if n >= 4 && string(buf[2:4]) == want {
+			return buf[:n]
+		}
+	}
+}
+
+// freePortPair returns a free UDP port whose next port is free too.
+func freePortPair(t *testing.T) int {
+	for {
+		probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatalf("Failed to find a free port: %v", err)
+		}
+		port := probe.LocalAddr().(*net.UDPAddr).Port
+		next, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port + 1})
+		probe.Close()
+		if err == nil {
+			next.Close()
+			return port
+		}
+	}
+}
+
+func TestRTPMidiReceiver_Session(t *testing.T) {
+	port := freePortPair(t)
+	control, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Fatalf("Failed to dial the control port: %v", err)
+	}
+	defer control.Close()
+	data, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port + 1})
+	if err != nil {
+		t.Fatalf("Failed to dial the data port: %v", err)
+	}
+	defer data.Close()
+
+	sendChannel := make(chan listener.MidiMessage, 8)
+	receiver := listener.NewRTPMidiReceiver(port, "lights", sendChannel)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- receiver.RunListener(ctx)
+	}()
+	defer func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("RunListener() error = %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("RunListener() did not return after cancellation")
+		}
+	}()
+
+	// A data port invitation without a control session is rejected.
+	exchange(t, data, sessionPacket(listener.APPLEMIDI_INVITATION, 7, "DAW"), listener.APPLEMIDI_REJECT)
+
+	for _, conn := range []*net.UDPConn{control, data} {
+		reply := exchange(t, conn, sessionPacket(listener.APPLEMIDI_INVITATION, 42, "DAW"), listener.APPLEMIDI_ACCEPT)
+		if token := binary.BigEndian.Uint32(reply[8:12]); token != 42 {
+			t.Errorf("Invitation reply token = %d, want 42", token)
+		}
+		if name := string(bytes.TrimRight(reply[16:], "\x00")); name != "lights" {
+			t.Errorf("Invitation reply name = %q, want %q", name, "lights")
+		}
+	}
+	if sessions := receiver.Sessions(); len(sessions) != 1 || sessions[0] != "DAW" {
+		t.Errorf("Sessions() = %v, want [DAW]", sessions)
+	}
+
+	// Clock sync: count 0 is answered with count 1 and the initiator timestamp kept.
+	sync := make([]byte, 36)
+	copy(sync, []byte{0xFF, 0xFF, 'C', 'K'})
+	binary.BigEndian.PutUint32(sync[4:8], initiatorSSRC)
+	binary.BigEndian.PutUint64(sync[12:20], 123456)
+	reply := exchange(t, data, sync, listener.APPLEMIDI_SYNC)
+	if reply[8] != 1 {
+		t.Errorf("Sync reply count = %d, want 1", reply[8])
+	}
+	if ts1 := binary.BigEndian.Uint64(reply[12:20]); ts1 != 123456 {
+		t.Errorf("Sync reply timestamp 1 = %d, want 123456", ts1)
+	}
+
+	// RTP-MIDI packet with running status, delta times, real-time and a recovery journal.
+	list := []byte{
+		0x92, 50, 127,
+		0x00, 50, 0,
+		0x81, 0x00, 0xB2, 7, 100,
+		0x00, 0xF8,
+	}
+	packet := []byte{0x80, 0x61, 0x00, 0x01, 0, 0, 0, 0}
+	packet = binary.BigEndian.AppendUint32(packet, initiatorSSRC)
+	packet = append(packet, 0x40|byte(len(list)))
+	packet = append(packet, list...)
+	packet = append(packet, 0x00, 0x01, 0x02, 0x90, 0x40) // Journal, never parsed.
+	data.Write(packet)
+
+	for _, want := range []listener.MidiMessage{
+		{Channel: 3, Note: 50, Velocity: 127, On: true},
+		{Channel: 3, Note: 50},
+		{Kind: listener.KIND_CONTROL_CHANGE, Channel: 3, Controller: 7, Value: 100},
+		{Kind: listener.KIND_CLOCK},
+	} {
+		select {
+		case message := <-sendChannel:
+			if message != want {
+				t.Errorf("Received %+v, want %+v", message, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for a message")
+		}
+	}
+
+	// Receiver feedback acknowledges the sequence number on the control port.
+	feedback := readCommand(t, control, listener.APPLEMIDI_FEEDBACK)
+	if sequence := binary.BigEndian.Uint16(feedback[8:10]); sequence != 1 {
+		t.Errorf("Feedback sequence = %d, want 1", sequence)
+	}
+
+	// Shutting down ends the session.
+	cancel()
+	if end := readCommand(t, control, listener.APPLEMIDI_END); binary.BigEndian.Uint32(end[8:12]) != 42 {
+		t.Errorf("Shutdown token = %d, want 42", binary.BigEndian.Uint32(end[8:12]))
+	}
+}
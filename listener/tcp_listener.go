@@ -0,0 +1,81 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+)
+
+// TCPMidiReceiver reads a raw MIDI 1.0 byte stream from every connection.
+type TCPMidiReceiver struct {
+	SendChannel chan MidiMessage
+	port        int
+}
+
+func (r *TCPMidiReceiver) RunListener(ctx context.Context) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", r.port))
+	if err != nil {
+		return err
+	}
+
+	// Closing the listener unblocks the pending accept.
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.serve(ctx, conn)
+		}()
+	}
+}
+
+// serve parses the stream of conn until it is closed or ctx is cancelled.
+func (r *TCPMidiReceiver) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var parser Parser
+	var buf [512]byte
+	var messages []MidiMessage
+	for {
+		n, err := conn.Read(buf[:])
+		messages = parser.Parse(messages[:0], buf[:n])
+		for _, message := range messages {
+			select {
+			case r.SendChannel <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				log.Printf("MIDI stream from %s: %v\n", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// NewTCPMidiReceiver sends the messages of every connection to sendChannel, shared with other receivers.
+func NewTCPMidiReceiver(port int, sendChannel chan MidiMessage) *TCPMidiReceiver {
+	return &TCPMidiReceiver{
+		SendChannel: sendChannel,
+		port:        port,
+	}
+}
@@ -0,0 +1,66 @@
+package listener_test
+
+import (
+	"context"
+	"ddp-sender/listener"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTCPMidiReceiver_RunListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	sendChannel := make(chan listener.MidiMessage, 8)
+	receiver := listener.NewTCPMidiReceiver(port, sendChannel)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- receiver.RunListener(ctx)
+	}()
+
+	var conn net.Conn
+	for start := time.Now(); time.Since(start) < 2*time.Second; time.Sleep(10 * time.Millisecond) {
+		if conn, err = net.Dial("tcp", l.Addr().String()); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// A note on split across writes, then a running status note off.
+	conn.Write([]byte{0x92, 50})
+	conn.Write([]byte{127, 50, 0})
+
+	for _, want := range []listener.MidiMessage{
+		{Channel: 3, Note: 50, Velocity: 127, On: true},
+		{Channel: 3, Note: 50},
+	} {
+		select {
+		case message := <-sendChannel:
+			if message != want {
+				t.Errorf("Received %+v, want %+v", message, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for a message")
+		}
+	}
+
+	// Cancelling closes the open connection too.
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunListener() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunListener() did not return after cancellation")
+	}
+}
@@ -1,56 +1,115 @@
 package listener
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"net"
+	"time"
+)
+
+// Raw streams are tracked by sender address, so running status needs a fixed source port.
+// Senders idle for RAW_SENDER_IDLE_TIMEOUT are forgotten when a new one shows up, and the
+// least recently seen one when MAX_RAW_SENDERS are tracked (e.g. a new port per datagram).
+const (
+	RAW_SENDER_IDLE_TIMEOUT = time.Minute
+	MAX_RAW_SENDERS         = 64
 )
 
 type UDPMidiReceiver struct {
 	SendChannel chan MidiMessage
-	conn        *net.UDPConn
+	// Raw reads the datagrams of every sender as a MIDI 1.0 byte stream instead of packets.
+	Raw     bool
+	parsers map[string]*rawSender // Stream state by sender address.
+	conn    *net.UDPConn
+	port    int
+}
+
+type rawSender struct {
+	parser   Parser
+	lastSeen time.Time
 }
 
-func (r UDPMidiReceiver) ReceiveMidi() error {
-	// Buffer size is 4 bytes per message (Note, Velocity, On/Off, Channel)
-	var buf [4]byte
-	n, _, err := r.conn.ReadFromUDP(buf[0:])
+// ReceiveMidi reads a legacy or versioned packet (see DecodePacket), or raw MIDI bytes, and sends
+// its messages until ctx is done.
+func (r UDPMidiReceiver) ReceiveMidi(ctx context.Context) error {
+	var buf [1500]byte
+	n, addr, err := r.conn.ReadFromUDP(buf[0:])
 	if err != nil {
 		return err
 	}
-	if n != 4 {
-		return fmt.Errorf("unexpected message size: %d bytes", n)
+	var messages []MidiMessage
+	if r.Raw {
+		messages = r.parser(addr.String(), time.Now()).Parse(messages, buf[:n])
+	} else {
+		messages, err = DecodePacket(buf[:n])
+		if err != nil {
+			return err
+		}
 	}
-	message := MidiMessage{
-		Note:     buf[0],
-		Velocity: buf[1],
-		On:       buf[2] == 1,
-		Channel:  buf[3],
+	for _, message := range messages {
+		select {
+		case r.SendChannel <- message:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	r.SendChannel <- message
 	return nil
 }
 
-func (r UDPMidiReceiver) RunListener() error {
+// parser returns the stream parser of the sender at addr, tracking it if it is new.
+func (r UDPMidiReceiver) parser(addr string, now time.Time) *Parser {
+	sender, ok := r.parsers[addr]
+	if !ok {
+		for key, other := range r.parsers {
+			if now.Sub(other.lastSeen) > RAW_SENDER_IDLE_TIMEOUT {
+				delete(r.parsers, key)
+			}
+		}
+		if len(r.parsers) >= MAX_RAW_SENDERS {
+			oldest := ""
+			for key, other := range r.parsers {
+				if oldest == "" || other.lastSeen.Before(r.parsers[oldest].lastSeen) {
+					oldest = key
+				}
+			}
+			delete(r.parsers, oldest)
+		}
+		sender = &rawSender{}
+		r.parsers[addr] = sender
+	}
+	sender.lastSeen = now
+	return &sender.parser
+}
+
+func (r UDPMidiReceiver) RunListener(ctx context.Context) error {
 	conn, err := net.ListenUDP("udp", &net.UDPAddr{
 		IP:   net.IPv4zero,
-		Port: 8090,
+		Port: r.port,
 	})
 	if err != nil {
 		return err
 	}
 	r.conn = conn
 
+	// Closing the socket unblocks the pending read.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
 	for {
-		err := r.ReceiveMidi()
+		err := r.ReceiveMidi(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
 		if err != nil {
 			log.Println(err)
 		}
 	}
 }
 
-func NewUDPMidiReceiver() *UDPMidiReceiver {
+func NewUDPMidiReceiver(port int) *UDPMidiReceiver {
 	return &UDPMidiReceiver{
 		SendChannel: make(chan MidiMessage, 255),
+		parsers:     make(map[string]*rawSender),
+		port:        port,
 	}
 }
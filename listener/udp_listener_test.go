@@ -1,6 +1,7 @@
 package listener_test
 
 import (
+	"context"
 	"net"
 	"testing"
 	"time"
@@ -19,10 +20,10 @@ func TestUDPMidiReceiver_RunListener(t *testing.T) {
 	}
 	defer conn.Close()
 
-	receiver := listener.NewUDPMidiReceiver()
+	receiver := listener.NewUDPMidiReceiver(8090)
 
 	go func() {
-		err := receiver.RunListener()
+		err := receiver.RunListener(context.Background())
 		if err != nil {
 			t.Errorf("RunListener failed: %v", err)
 		}
@@ -56,3 +57,103 @@ func TestUDPMidiReceiver_RunListener(t *testing.T) {
 		t.Fatalf("Received message is not correct: %v", receivedMessage)
 	}
 }
+
+func TestUDPMidiReceiver_RawSenders(t *testing.T) {
+	// Pick a free port for the listener.
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP: %v", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	receiver := listener.NewUDPMidiReceiver(port)
+	receiver.Raw = true
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		receiver.RunListener(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	time.Sleep(100 * time.Millisecond) // Wait to ensure the listener is set up before sending data.
+
+	send := func(data ...byte) *net.UDPConn {
+		conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+		if err != nil {
+			t.Fatalf("Failed to dial UDP: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		if _, err := conn.Write(data); err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+		return conn
+	}
+
+	// The first sender is evicted by as many newer senders as are tracked, each on its own port.
+	first := send(0x90, 60, 100)
+	var last *net.UDPConn
+	for i := 0; i < listener.MAX_RAW_SENDERS; i++ {
+		last = send(0x90, 61, 100)
+	}
+	// Running status is lost for the evicted sender and kept for the most recent one.
+	if _, err := first.Write([]byte{62, 100}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if _, err := last.Write([]byte{63, 100}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+
+	notes := make(map[uint8]int)
+	for notes[63] == 0 {
+		select {
+		case message := <-receiver.SendChannel:
+			notes[message.Note]++
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out, received notes %v", notes)
+		}
+	}
+	if notes[60] != 1 || notes[61] != listener.MAX_RAW_SENDERS || notes[62] != 0 {
+		t.Errorf("Received notes %v, want 60 once, 61 from every sender and no 62", notes)
+	}
+}
+
+func TestUDPMidiReceiver_StopsWhileSending(t *testing.T) {
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP: %v", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	// Nobody reads the messages, as after the updater has stopped.
+	receiver := listener.NewUDPMidiReceiver(port)
+	receiver.SendChannel = make(chan listener.MidiMessage)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		receiver.RunListener(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond) // Wait to ensure the listener is set up before sending data.
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Fatalf("Failed to dial UDP: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{50, 127, 1, 1}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunListener did not return while sending a message")
+	}
+}
@@ -0,0 +1,167 @@
+package listener
+
+import "fmt"
+
+// UDP packets come in two formats:
+//
+//   - Legacy, 4 bytes: note, velocity, on (1) or off, channel (1-16).
+//   - Versioned: WIRE_VERSION_2 followed by one or more MIDI 1.0 messages, each a status
+//     byte (channel 0-15 in the low nibble) and its data bytes, without running status.
+//
+// Legacy packets start with a note (0-127), the version byte (>= 0x80) tells them apart.
+const WIRE_VERSION_2 = 0x82
+
+// MIDI 1.0 status bytes, the low nibble of channel messages is the channel.
+const (
+	STATUS_NOTE_OFF           = 0x80
+	STATUS_NOTE_ON            = 0x90
+	STATUS_POLY_AFTERTOUCH    = 0xA0
+	STATUS_CONTROL_CHANGE     = 0xB0
+	STATUS_PROGRAM_CHANGE     = 0xC0
+	STATUS_CHANNEL_AFTERTOUCH = 0xD0
+	STATUS_PITCH_BEND         = 0xE0
+	STATUS_SYSEX              = 0xF0
+	STATUS_END_OF_SYSEX       = 0xF7
+	STATUS_CLOCK              = 0xF8
+	STATUS_START              = 0xFA
+	STATUS_CONTINUE           = 0xFB
+	STATUS_STOP               = 0xFC
+	STATUS_ACTIVE_SENSING     = 0xFE
+	STATUS_RESET              = 0xFF
+)
+
+// DecodePacket returns the messages of a legacy or versioned UDP packet.
+func DecodePacket(packet []byte) ([]MidiMessage, error) {
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("empty packet")
+	}
+	if packet[0] < 0x80 {
+		if len(packet) != 4 {
+			return nil, fmt.Errorf("unexpected message size: %d bytes", len(packet))
+		}
+		return []MidiMessage{{
+			Note:     packet[0],
+			Velocity: packet[1],
+			On:       packet[2] == 1,
+			Channel:  packet[3],
+		}}, nil
+	}
+	if packet[0] != WIRE_VERSION_2 {
+		return nil, fmt.Errorf("unsupported packet version 0x%02x", packet[0])
+	}
+
+	var messages []MidiMessage
+	for data := packet[1:]; len(data) > 0; {
+		status := data[0]
+		length := dataLength(status)
+		if length < 0 {
+			return nil, fmt.Errorf("unsupported status byte 0x%02x", status)
+		}
+		if len(data) < 1+length {
+			return nil, fmt.Errorf("truncated message 0x%02x", status)
+		}
+		for _, b := range data[1 : 1+length] {
+			if b >= 0x80 {
+				return nil, fmt.Errorf("invalid data byte 0x%02x in message 0x%02x", b, status)
+			}
+		}
+		messages = append(messages, decodeMIDI(status, data[1:1+length]))
+		data = data[1+length:]
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("packet has no messages")
+	}
+	return messages, nil
+}
+
+// EncodePacket returns a versioned UDP packet carrying messages.
+func EncodePacket(messages ...MidiMessage) []byte {
+	packet := []byte{WIRE_VERSION_2}
+	for _, message := range messages {
+		packet = AppendMIDI(packet, message)
+	}
+	return packet
+}
+
+// AppendMIDI appends the MIDI 1.0 bytes of message to dst. Channel messages outside
+// channels 1-16 have no status byte and are skipped.
+func AppendMIDI(dst []byte, message MidiMessage) []byte {
+	if !message.Kind.IsRealtime() && (message.Channel < 1 || message.Channel > 16) {
+		return dst
+	}
+	channel := message.Channel - 1
+	switch message.Kind {
+	case KIND_NOTE:
+		if message.On {
+			return append(dst, STATUS_NOTE_ON|channel, message.Note&0x7F, message.Velocity&0x7F)
+		}
+		return append(dst, STATUS_NOTE_OFF|channel, message.Note&0x7F, message.Velocity&0x7F)
+	case KIND_POLY_AFTERTOUCH:
+		return append(dst, STATUS_POLY_AFTERTOUCH|channel, message.Note&0x7F, message.Value&0x7F)
+	case KIND_CONTROL_CHANGE:
+		return append(dst, STATUS_CONTROL_CHANGE|channel, message.Controller&0x7F, message.Value&0x7F)
+	case KIND_PROGRAM_CHANGE:
+		return append(dst, STATUS_PROGRAM_CHANGE|channel, message.Value&0x7F)
+	case KIND_CHANNEL_AFTERTOUCH:
+		return append(dst, STATUS_CHANNEL_AFTERTOUCH|channel, message.Value&0x7F)
+	case KIND_PITCH_BEND:
+		bend := int(message.Bend) + 8192
+		return append(dst, STATUS_PITCH_BEND|channel, byte(bend&0x7F), byte(bend>>7&0x7F))
+	case KIND_CLOCK:
+		return append(dst, STATUS_CLOCK)
+	case KIND_START:
+		return append(dst, STATUS_START)
+	case KIND_CONTINUE:
+		return append(dst, STATUS_CONTINUE)
+	case KIND_STOP:
+		return append(dst, STATUS_STOP)
+	}
+	return dst
+}
+
+// dataLength returns the amount of data bytes following a supported status byte, -1 otherwise.
+func dataLength(status byte) int {
+	switch status & 0xF0 {
+	case STATUS_NOTE_OFF, STATUS_NOTE_ON, STATUS_POLY_AFTERTOUCH, STATUS_CONTROL_CHANGE, STATUS_PITCH_BEND:
+		return 2
+	case STATUS_PROGRAM_CHANGE, STATUS_CHANNEL_AFTERTOUCH:
+		return 1
+	}
+	switch status {
+	case STATUS_CLOCK, STATUS_START, STATUS_CONTINUE, STATUS_STOP:
+		return 0
+	}
+	return -1
+}
+
+// decodeMIDI converts a supported status byte and its data bytes into a message.
+func decodeMIDI(status byte, data []byte) MidiMessage {
+	channel := status&0x0F + 1
+	switch status & 0xF0 {
+	case STATUS_NOTE_OFF:
+		return MidiMessage{Kind: KIND_NOTE, Channel: channel, Note: data[0], Velocity: data[1]}
+	case STATUS_NOTE_ON:
+		// Note on with velocity 0 is a note off.
+		return MidiMessage{Kind: KIND_NOTE, Channel: channel, Note: data[0], Velocity: data[1], On: data[1] > 0}
+	case STATUS_POLY_AFTERTOUCH:
+		return MidiMessage{Kind: KIND_POLY_AFTERTOUCH, Channel: channel, Note: data[0], Value: data[1]}
+	case STATUS_CONTROL_CHANGE:
+		return MidiMessage{Kind: KIND_CONTROL_CHANGE, Channel: channel, Controller: data[0], Value: data[1]}
+	case STATUS_PROGRAM_CHANGE:
+		return MidiMessage{Kind: KIND_PROGRAM_CHANGE, Channel: channel, Value: data[0]}
+	case STATUS_CHANNEL_AFTERTOUCH:
+		return MidiMessage{Kind: KIND_CHANNEL_AFTERTOUCH, Channel: channel, Value: data[0]}
+	case STATUS_PITCH_BEND:
+		return MidiMessage{Kind: KIND_PITCH_BEND, Channel: channel, Bend: int16(int(data[1])<<7|int(data[0])) - 8192}
+	}
+	switch status {
+	case STATUS_CLOCK:
+		return MidiMessage{Kind: KIND_CLOCK}
+	case STATUS_START:
+		return MidiMessage{Kind: KIND_START}
+	case STATUS_CONTINUE:
+		return MidiMessage{Kind: KIND_CONTINUE}
+	default:
+		return MidiMessage{Kind: KIND_STOP}
+	}
+}
@@ -0,0 +1,101 @@
+package listener_test
+
+import (
+	"ddp-sender/listener"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecodePacket_Legacy(t *testing.T) {
+	messages, err := listener.DecodePacket([]byte{50, 127, 1, 2})
+	if err != nil {
+		t.Fatalf("DecodePacket() error = %v", err)
+	}
+	want := []listener.MidiMessage{{Note: 50, Velocity: 127, On: true, Channel: 2}}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("DecodePacket() = %+v, want %+v", messages, want)
+	}
+}
+
+func TestEncodePacket_RoundTrip(t *testing.T) {
+	messages := []listener.MidiMessage{
+		{Kind: listener.KIND_NOTE, Channel: 3, Note: 60, Velocity: 100, On: true},
+		{Kind: listener.KIND_NOTE, Channel: 3, Note: 60, Velocity: 64},
+		{Kind: listener.KIND_POLY_AFTERTOUCH, Channel: 3, Note: 60, Value: 90},
+		{Kind: listener.KIND_CONTROL_CHANGE, Channel: 16, Controller: 7, Value: 127},
+		{Kind: listener.KIND_PROGRAM_CHANGE, Channel: 1, Value: 5},
+		{Kind: listener.KIND_CHANNEL_AFTERTOUCH, Channel: 2, Value: 33},
+		{Kind: listener.KIND_PITCH_BEND, Channel: 1, Bend: -8192},
+		{Kind: listener.KIND_PITCH_BEND, Channel: 1, Bend: 8191},
+		{Kind: listener.KIND_CLOCK},
+		{Kind: listener.KIND_START},
+		{Kind: listener.KIND_CONTINUE},
+		{Kind: listener.KIND_STOP},
+	}
+
+	decoded, err := listener.DecodePacket(listener.EncodePacket(messages...))
+	if err != nil {
+		t.Fatalf("DecodePacket() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, messages) {
+		t.Errorf("DecodePacket() = %+v, want %+v", decoded, messages)
+	}
+}
+
+func TestEncodePacket_SkipsInvalidChannels(t *testing.T) {
+	packet := listener.EncodePacket(
+		listener.MidiMessage{Kind: listener.KIND_NOTE, Channel: 0, Note: 60, Velocity: 100, On: true},
+		listener.MidiMessage{Kind: listener.KIND_CONTROL_CHANGE, Channel: 17, Controller: 7, Value: 127},
+		listener.MidiMessage{Kind: listener.KIND_CLOCK},
+	)
+	if want := []byte{listener.WIRE_VERSION_2, listener.STATUS_CLOCK}; !reflect.DeepEqual(packet, want) {
+		t.Errorf("EncodePacket() = % x, want % x", packet, want)
+	}
+}
+
+func TestDecodePacket_NoteOnZeroVelocityIsOff(t *testing.T) {
+	messages, err := listener.DecodePacket([]byte{listener.WIRE_VERSION_2, 0x92, 40, 0})
+	if err != nil {
+		t.Fatalf("DecodePacket() error = %v", err)
+	}
+	if want := (listener.MidiMessage{Channel: 3, Note: 40}); messages[0] != want {
+		t.Errorf("DecodePacket() = %+v, want %+v", messages[0], want)
+	}
+}
+
+func TestDecodePacket_Errors(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":            {},
+		"legacy size":      {50, 127, 1},
+		"unknown version":  {0x83, 0x90, 1, 1},
+		"no messages":      {listener.WIRE_VERSION_2},
+		"truncated":        {listener.WIRE_VERSION_2, 0x90, 60},
+		"data byte status": {listener.WIRE_VERSION_2, 0x90, 60, 0x90},
+		"sysex":            {listener.WIRE_VERSION_2, 0xF0, 1, 0xF7},
+	}
+	for name, packet := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := listener.DecodePacket(packet); err == nil {
+				t.Errorf("DecodePacket(% x) error = nil, want error", packet)
+			}
+		})
+	}
+}
+
+func TestMidiMessage_JSONKind(t *testing.T) {
+	var message listener.MidiMessage
+	if err := json.Unmarshal([]byte(`{"kind": "control_change", "channel": 3, "controller": 1, "value": 64}`), &message); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := listener.MidiMessage{Kind: listener.KIND_CONTROL_CHANGE, Channel: 3, Controller: 1, Value: 64}
+	if message != want {
+		t.Errorf("Unmarshal() = %+v, want %+v", message, want)
+	}
+
+	// Notes keep the original JSON format.
+	data, _ := json.Marshal(listener.MidiMessage{Note: 1, Velocity: 2, On: true, Channel: 1})
+	if string(data) != `{"note":1,"velocity":2,"on":true,"channel":1}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
@@ -1,79 +1,38 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
 	"log"
-	"sync/atomic"
-	"time"
-
-	"github.com/coral/ddp"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"ddp-sender/app"
 	"ddp-sender/config"
-	"ddp-sender/led"
-	"ddp-sender/listener"
-	"ddp-sender/updater"
-	"ddp-sender/webserver"
 )
 
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	ddpClient := ddp.NewDDPController()
-
-	ddpClient.ConnectUDP(config.DDP_ENDPOINT)
-
-	midiReceiver := listener.NewUDPMidiReceiver()
-
-	// ledArray := led.NewLEDArray()
-	ledArray := led.NewLEDArrayColor(config.LED_AMOUNT)
-
-	updater := updater.NewUpdater(ledArray, midiReceiver.SendChannel)
-	// Run LED updater (MIDI-LED mapper)
-	go updater.Run()
-
-	// Run LED ticker (dynamic effects)
-	go updater.Ticker(config.LED_REFRESH_RATE)
-
-	// Start web server
-	go func() {
-		webServer := webserver.NewWebServer(updater.GetCustomMapper())
-		err := webServer.Start()
-		if err != nil {
-			log.Printf("Web server error: %v", err)
-		}
-	}()
-
-	// DDP Sender
-	go func() {
-		log.Println("Launched DDP Sender")
-		var updateCount atomic.Int64
-
-		// DDP writes/s monitoring
-		go func(counter *atomic.Int64) {
-			for range time.Tick(time.Duration(config.MONITOR_TICKER_INTERVAL) * time.Second) {
-				count := counter.Swap(0)
-				log.Printf("DDP - %d updates/s (avg %ds)\n", (count)/int64(config.MONITOR_TICKER_INTERVAL), config.MONITOR_TICKER_INTERVAL)
-			}
-		}(&updateCount)
+	// SIGINT/SIGTERM stop every component and blank the LEDs before exiting.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		// Start sending DDP arrays.
-		for {
-			start := time.Now()
-			_, err := ddpClient.Write(ledArray.GetArray())
-			if err != nil {
-				fmt.Println(err)
-			}
-			updateCount.Add(1)
-			if time.Since(start) > 3*time.Millisecond {
-				log.Printf("DDPSender.Write() -> %s\n", time.Since(start))
-			}
-			// fmt.Printf("%v\n", ledArray.LedStatus)
-			// fmt.Println(written, err)
-			time.Sleep(config.LED_REFRESH_RATE)
-		}
-	}()
-	log.Println("Launched MidiListener")
-	err := midiReceiver.RunListener()
+	application, err := app.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = application.Run(ctx)
 	if err != nil {
-		log.Println(err)
+		log.Fatal(err)
 	}
+	log.Println("Shutdown complete")
 }
@@ -0,0 +1,292 @@
+// Package osc encodes and decodes Open Sound Control 1.0 packets.
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Packet is a Message or a Bundle.
+type Packet interface {
+	MarshalBinary() ([]byte, error)
+}
+
+// Message is an OSC message. Arguments are int32, float32, string, []byte (blob), int64,
+// float64, bool, nil or TimeTag.
+type Message struct {
+	Address   string
+	Arguments []any
+}
+
+// Bundle groups packets to be handled at Time, atomically.
+type Bundle struct {
+	Time     TimeTag
+	Elements []Packet
+}
+
+// TimeTag is an NTP timestamp: seconds since 1900 in the upper 32 bits, fraction in the lower.
+type TimeTag uint64
+
+// IMMEDIATE is the time tag of bundles handled as soon as they are received.
+const IMMEDIATE TimeTag = 1
+
+const (
+	bundleTag = "#bundle"
+	ntpEpoch  = 2208988800 // Seconds from 1900 to 1970.
+)
+
+// NewTimeTag returns the time tag of t.
+func NewTimeTag(t time.Time) TimeTag {
+	seconds := uint64(t.Unix() + ntpEpoch)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return TimeTag(seconds<<32 | fraction)
+}
+
+// Time returns the time of the tag, the zero time for IMMEDIATE.
+func (t TimeTag) Time() time.Time {
+	if t == IMMEDIATE {
+		return time.Time{}
+	}
+	seconds := int64(t>>32) - ntpEpoch
+	nanoseconds := (uint64(t) & 0xFFFFFFFF) * uint64(time.Second) >> 32
+	return time.Unix(seconds, int64(nanoseconds))
+}
+
+// Number returns argument i as a float64 when it is numeric or a bool (1 or 0).
+func (m Message) Number(i int) (float64, bool) {
+	if i >= len(m.Arguments) {
+		return 0, false
+	}
+	switch v := m.Arguments[i].(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// String returns argument i when it is a string.
+func (m Message) String(i int) (string, bool) {
+	if i >= len(m.Arguments) {
+		return "", false
+	}
+	s, ok := m.Arguments[i].(string)
+	return s, ok
+}
+
+func (m Message) MarshalBinary() ([]byte, error) {
+	if len(m.Address) == 0 || m.Address[0] != '/' {
+		return nil, fmt.Errorf("address %q must start with /", m.Address)
+	}
+	tags := []byte{','}
+	var arguments []byte
+	for _, argument := range m.Arguments {
+		switch v := argument.(type) {
+		case int32:
+			tags = append(tags, 'i')
+			arguments = binary.BigEndian.AppendUint32(arguments, uint32(v))
+		case float32:
+			tags = append(tags, 'f')
+			arguments = binary.BigEndian.AppendUint32(arguments, math.Float32bits(v))
+		case string:
+			tags = append(tags, 's')
+			arguments = appendString(arguments, v)
+		case []byte:
+			tags = append(tags, 'b')
+			arguments = binary.BigEndian.AppendUint32(arguments, uint32(len(v)))
+			arguments = append(arguments, v...)
+			arguments = pad(arguments)
+		case int64:
+			tags = append(tags, 'h')
+			arguments = binary.BigEndian.AppendUint64(arguments, uint64(v))
+		case float64:
+			tags = append(tags, 'd')
+			arguments = binary.BigEndian.AppendUint64(arguments, math.Float64bits(v))
+		case TimeTag:
+			tags = append(tags, 't')
+			arguments = binary.BigEndian.AppendUint64(arguments, uint64(v))
+		case bool:
+			if v {
+				tags = append(tags, 'T')
+			} else {
+				tags = append(tags, 'F')
+			}
+		case nil:
+			tags = append(tags, 'N')
+		default:
+			return nil, fmt.Errorf("unsupported argument type %T", argument)
+		}
+	}
+
+	data := appendString(nil, m.Address)
+	data = appendString(data, string(tags))
+	return append(data, arguments...), nil
+}
+
+func (b Bundle) MarshalBinary() ([]byte, error) {
+	data := appendString(nil, bundleTag)
+	data = binary.BigEndian.AppendUint64(data, uint64(b.Time))
+	for _, element := range b.Elements {
+		content, err := element.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		data = binary.BigEndian.AppendUint32(data, uint32(len(content)))
+		data = append(data, content...)
+	}
+	return data, nil
+}
+
+// Decode returns the *Message or *Bundle encoded in data.
+func Decode(data []byte) (Packet, error) {
+	if len(data) == 0 || len(data)%4 != 0 {
+		return nil, fmt.Errorf("packet size %d is not a positive multiple of 4", len(data))
+	}
+	if data[0] == '#' {
+		return decodeBundle(data)
+	}
+	return decodeMessage(data)
+}
+
+func decodeBundle(data []byte) (*Bundle, error) {
+	tag, data, err := readString(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag != bundleTag {
+		return nil, fmt.Errorf("unknown packet %q", tag)
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("bundle without time tag")
+	}
+	bundle := &Bundle{Time: TimeTag(binary.BigEndian.Uint64(data))}
+	for data = data[8:]; len(data) > 0; {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("truncated bundle element size")
+		}
+		size := binary.BigEndian.Uint32(data)
+		if uint64(size) > uint64(len(data)-4) {
+			return nil, fmt.Errorf("bundle element size %d exceeds the packet", size)
+		}
+		element, err := Decode(data[4 : 4+size])
+		if err != nil {
+			return nil, err
+		}
+		bundle.Elements = append(bundle.Elements, element)
+		data = data[4+size:]
+	}
+	return bundle, nil
+}
+
+func decodeMessage(data []byte) (*Message, error) {
+	address, data, err := readString(data)
+	if err != nil {
+		return nil, err
+	}
+	if address == "" || address[0] != '/' {
+		return nil, fmt.Errorf("address %q must start with /", address)
+	}
+	message := &Message{Address: address}
+	if len(data) == 0 {
+		// Very old senders omit the type tag string when there are no arguments.
+		return message, nil
+	}
+	tags, data, err := readString(data)
+	if err != nil {
+		return nil, err
+	}
+	if tags == "" || tags[0] != ',' {
+		return nil, fmt.Errorf("type tags %q must start with ,", tags)
+	}
+
+	for _, tag := range []byte(tags[1:]) {
+		var argument any
+		size := 0
+		switch tag {
+		case 'i', 'f', 'c', 'r', 'm':
+			size = 4
+		case 'h', 'd', 't':
+			size = 8
+		}
+		if len(data) < size {
+			return nil, fmt.Errorf("truncated argument %q", tag)
+		}
+		switch tag {
+		case 'i', 'c', 'r', 'm':
+			// Characters, colors and MIDI messages are read as plain 32-bit values.
+			argument = int32(binary.BigEndian.Uint32(data))
+		case 'f':
+			argument = math.Float32frombits(binary.BigEndian.Uint32(data))
+		case 'h':
+			argument = int64(binary.BigEndian.Uint64(data))
+		case 'd':
+			argument = math.Float64frombits(binary.BigEndian.Uint64(data))
+		case 't':
+			argument = TimeTag(binary.BigEndian.Uint64(data))
+		case 's', 'S':
+			argument, data, err = readString(data)
+			if err != nil {
+				return nil, err
+			}
+		case 'b':
+			if len(data) < 4 {
+				return nil, fmt.Errorf("truncated blob size")
+			}
+			length := binary.BigEndian.Uint32(data)
+			if uint64(length) > uint64(len(data)-4) {
+				return nil, fmt.Errorf("blob size %d exceeds the packet", length)
+			}
+			argument = bytes.Clone(data[4 : 4+length])
+			data = data[min(len(data), 4+paddedLength(int(length))):]
+		case 'T':
+			argument = true
+		case 'F':
+			argument = false
+		case 'N', 'I':
+			argument = nil
+		default:
+			return nil, fmt.Errorf("unsupported type tag %q", tag)
+		}
+		data = data[size:]
+		message.Arguments = append(message.Arguments, argument)
+	}
+	return message, nil
+}
+
+// readString reads a NUL terminated string padded to 4 bytes.
+func readString(data []byte) (string, []byte, error) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", nil, fmt.Errorf("unterminated string")
+	}
+	return string(data[:end]), data[min(len(data), paddedLength(end+1)):], nil
+}
+
+func appendString(dst []byte, s string) []byte {
+	return pad(append(append(dst, s...), 0))
+}
+
+// pad appends zeros up to a multiple of 4 bytes.
+func pad(data []byte) []byte {
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+func paddedLength(length int) int {
+	return (length + 3) &^ 3
+}
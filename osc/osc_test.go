@@ -0,0 +1,120 @@
+package osc_test
+
+import (
+	"bytes"
+	"ddp-sender/osc"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMessage_RoundTrip(t *testing.T) {
+	tests := []osc.Message{
+		{Address: "/a"},
+		{Address: "/abc"}, // Address exactly filling 4 bytes gets a full padding word.
+		{Address: "/note/60", Arguments: []any{float32(0.5)}},
+		{Address: "/all", Arguments: []any{
+			int32(-7), float32(1.25), "abc", "abcd", []byte{1, 2, 3}, []byte{},
+			int64(1 << 40), 3.5, true, false, nil, osc.IMMEDIATE,
+		}},
+	}
+	for _, message := range tests {
+		t.Run(message.Address, func(t *testing.T) {
+			data, err := message.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			if len(data)%4 != 0 {
+				t.Errorf("MarshalBinary() size = %d, want a multiple of 4", len(data))
+			}
+			packet, err := osc.Decode(data)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(packet, &message) {
+				t.Errorf("Decode() = %+v, want %+v", packet, &message)
+			}
+		})
+	}
+}
+
+func TestMessage_Encoding(t *testing.T) {
+	data, err := osc.Message{Address: "/foo", Arguments: []any{int32(1000), "hi"}}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	want := []byte{
+		'/', 'f', 'o', 'o', 0, 0, 0, 0,
+		',', 'i', 's', 0,
+		0, 0, 0x03, 0xE8,
+		'h', 'i', 0, 0,
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("MarshalBinary() = % x, want % x", data, want)
+	}
+}
+
+func TestBundle_RoundTrip(t *testing.T) {
+	bundle := &osc.Bundle{
+		Time: osc.NewTimeTag(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)),
+		Elements: []osc.Packet{
+			&osc.Message{Address: "/mapping/switch", Arguments: []any{"default.json"}},
+			&osc.Bundle{Time: osc.IMMEDIATE, Elements: []osc.Packet{
+				&osc.Message{Address: "/note/1", Arguments: []any{int32(127)}},
+			}},
+		},
+	}
+	data, err := bundle.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	packet, err := osc.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(packet, bundle) {
+		t.Errorf("Decode() = %+v, want %+v", packet, bundle)
+	}
+}
+
+func TestTimeTag_Time(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 15, 250_000_000, time.UTC)
+	if got := osc.NewTimeTag(now).Time(); got.Sub(now).Abs() > time.Microsecond {
+		t.Errorf("NewTimeTag().Time() = %v, want %v", got, now)
+	}
+	if got := osc.IMMEDIATE.Time(); !got.IsZero() {
+		t.Errorf("IMMEDIATE.Time() = %v, want the zero time", got)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":              {},
+		"unaligned":          {'/', 'a', 0},
+		"no slash":           {'a', 0, 0, 0},
+		"unterminated":       {'/', 'a', 'b', 'c'},
+		"unknown tag":        {'/', 'a', 0, 0, ',', 'x', 0, 0},
+		"truncated int":      {'/', 'a', 0, 0, ',', 'i', 0, 0},
+		"blob too long":      {'/', 'a', 0, 0, ',', 'b', 0, 0, 0, 0, 0, 8, 1, 2, 3, 4},
+		"unknown packet":     {'#', 'x', 0, 0},
+		"element too long":   append([]byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01"), 0, 0, 0, 8, '/', 'a', 0, 0),
+		"element unaligned":  append([]byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01"), 0, 0, 0, 3, '/', 'a', 0, 0),
+		"bundle without tag": []byte("#bundle\x00"),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := osc.Decode(data); err == nil {
+				t.Errorf("Decode(% x) error = nil, want error", data)
+			}
+		})
+	}
+}
+
+func TestMessage_MarshalBinaryErrors(t *testing.T) {
+	if _, err := (osc.Message{Address: "note"}).MarshalBinary(); err == nil {
+		t.Error("MarshalBinary() without / error = nil, want error")
+	}
+	if _, err := (osc.Message{Address: "/a", Arguments: []any{1}}).MarshalBinary(); err == nil {
+		t.Error("MarshalBinary() with an int error = nil, want error")
+	}
+}
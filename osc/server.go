@@ -0,0 +1,94 @@
+package osc
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+)
+
+// MAX_BUNDLE_DELAY bounds how far in the future a bundle can be scheduled, later bundles are dropped.
+const MAX_BUNDLE_DELAY = time.Minute
+
+// Handler handles one message, errors are logged with its address.
+type Handler func(message Message) error
+
+// Server receives OSC packets over UDP. Messages are handled on arrival, bundles at their
+// time tag: the messages of a bundle are handled in order, after the bundle it is nested in.
+type Server struct {
+	port    int
+	handler Handler
+}
+
+func (s *Server) RunListener(ctx context.Context) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: s.port})
+	if err != nil {
+		return err
+	}
+	// Closing the socket unblocks the pending read.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	buf := make([]byte, 65507)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		packet, err := Decode(buf[:n])
+		if err != nil {
+			log.Printf("OSC packet from %s: %v\n", addr, err)
+			continue
+		}
+		s.Dispatch(ctx, packet)
+	}
+}
+
+// Dispatch handles packet, scheduling the bundles with a future time tag.
+func (s *Server) Dispatch(ctx context.Context, packet Packet) {
+	s.dispatch(ctx, packet, time.Time{})
+}
+
+// dispatch handles packet no earlier than after, the time of the enclosing bundle.
+func (s *Server) dispatch(ctx context.Context, packet Packet, after time.Time) {
+	switch p := packet.(type) {
+	case *Message:
+		if err := s.handler(*p); err != nil {
+			log.Printf("OSC %s: %v\n", p.Address, err)
+		}
+	case *Bundle:
+		at := p.Time.Time()
+		if at.Before(after) {
+			at = after
+		}
+		delay := time.Until(at)
+		if delay > MAX_BUNDLE_DELAY {
+			log.Printf("OSC bundle dropped: scheduled %s ahead, more than %s\n", delay.Round(time.Second), MAX_BUNDLE_DELAY)
+			return
+		}
+		handle := func() {
+			for _, element := range p.Elements {
+				if ctx.Err() != nil {
+					return
+				}
+				s.dispatch(ctx, element, at)
+			}
+		}
+		if delay <= 0 {
+			handle()
+			return
+		}
+		time.AfterFunc(delay, handle)
+	}
+}
+
+// NewServer listens for OSC packets on port, handing every message to handler.
+func NewServer(port int, handler Handler) *Server {
+	return &Server{
+		port:    port,
+		handler: handler,
+	}
+}
@@ -0,0 +1,115 @@
+package osc_test
+
+import (
+	"context"
+	"ddp-sender/osc"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServer_Dispatch(t *testing.T) {
+	received := make(chan string, 8)
+	server := osc.NewServer(0, func(message osc.Message) error {
+		received <- message.Address
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The later bundle is sent first, messages are handled in time tag order.
+	start := time.Now()
+	server.Dispatch(ctx, &osc.Bundle{
+		Time: osc.NewTimeTag(start.Add(100 * time.Millisecond)),
+		Elements: []osc.Packet{
+			&osc.Message{Address: "/late/1"},
+			// A nested bundle due earlier waits for its parent.
+			&osc.Bundle{Time: osc.IMMEDIATE, Elements: []osc.Packet{&osc.Message{Address: "/late/2"}}},
+		},
+	})
+	server.Dispatch(ctx, &osc.Bundle{
+		Time:     osc.NewTimeTag(start.Add(-time.Second)),
+		Elements: []osc.Packet{&osc.Message{Address: "/past"}},
+	})
+	server.Dispatch(ctx, &osc.Message{Address: "/now"})
+	server.Dispatch(ctx, &osc.Bundle{
+		Time:     osc.NewTimeTag(start.Add(2 * osc.MAX_BUNDLE_DELAY)),
+		Elements: []osc.Packet{&osc.Message{Address: "/dropped"}},
+	})
+
+	for _, want := range []string{"/past", "/now", "/late/1", "/late/2"} {
+		select {
+		case address := <-received:
+			if address != want {
+				t.Errorf("Handled %s, want %s", address, want)
+			}
+			if address == "/late/1" && time.Since(start) < 100*time.Millisecond {
+				t.Errorf("Handled %s after %s, before its time tag", address, time.Since(start))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s", want)
+		}
+	}
+	select {
+	case address := <-received:
+		t.Errorf("Handled %s, want nothing more", address)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestServer_RunListener(t *testing.T) {
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port
+	probe.Close()
+
+	received := make(chan osc.Message, 1)
+	server := osc.NewServer(port, func(message osc.Message) error {
+		// Resent duplicates are dropped.
+		select {
+		case received <- message:
+		default:
+		}
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- server.RunListener(ctx)
+	}()
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	data, _ := osc.Message{Address: "/master/brightness", Arguments: []any{float32(0.5)}}.MarshalBinary()
+
+	// Resend until the listener is bound.
+	var message osc.Message
+	for start := time.Now(); message.Address == ""; {
+		if time.Since(start) > 2*time.Second {
+			t.Fatal("Timed out waiting for a message")
+		}
+		conn.Write(data)
+		select {
+		case message = <-received:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if level, _ := message.Number(0); message.Address != "/master/brightness" || level != 0.5 {
+		t.Errorf("Received %+v, want /master/brightness 0.5", message)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunListener() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunListener() did not return after cancellation")
+	}
+}
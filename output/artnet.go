@@ -0,0 +1,245 @@
+package output
+
+import (
+	"bytes"
+	"ddp-sender/config"
+	"encoding/binary"
+	"log"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	ARTNET_PORT           = 6454
+	ARTNET_POLL_INTERVAL  = 3 * time.Second
+	artNetProtocolVersion = 14
+	artNetHeaderLength    = 18
+	artNetOpPoll          = 0x2000
+	artNetOpPollReply     = 0x2100
+	artNetOpDmx           = 0x5000
+	artNetOpSync          = 0x5200
+)
+
+var artNetID = [8]byte{'A', 'r', 't', '-', 'N', 'e', 't', 0}
+
+// ArtNetNode is a node that answered an ArtPoll.
+type ArtNetNode struct {
+	IP        string    `json:"ip"`
+	ShortName string    `json:"shortName"`
+	LongName  string    `json:"longName"`
+	LastSeen  time.Time `json:"lastSeen"`
+	source    string    // Address the reply came from.
+}
+
+// ArtNetDiscovery polls the destinations of the Art-Net drivers for nodes. Nodes answer
+// ArtPoll on the Art-Net port, which can only be bound once, so the drivers share it.
+type ArtNetDiscovery struct {
+	conn *net.UDPConn
+
+	lock         sync.RWMutex
+	destinations []net.IP
+	nodes        map[string]ArtNetNode
+	done         chan struct{}
+}
+
+func NewArtNetDiscovery() (*ArtNetDiscovery, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: ARTNET_PORT})
+	if err != nil {
+		return nil, err
+	}
+	a := &ArtNetDiscovery{
+		conn:  conn,
+		nodes: make(map[string]ArtNetNode),
+		done:  make(chan struct{}),
+	}
+	go a.receive()
+	go a.poll()
+	return a, nil
+}
+
+// add polls destination from the next poll on.
+func (a *ArtNetDiscovery) add(destination net.IP) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if !slices.ContainsFunc(a.destinations, destination.Equal) {
+		a.destinations = append(a.destinations, destination)
+	}
+}
+
+func (a *ArtNetDiscovery) poll() {
+	ticker := time.NewTicker(ARTNET_POLL_INTERVAL)
+	defer ticker.Stop()
+	// ArtPoll: header with Flags and DiagPriority 0, no reply on change and no diagnostics.
+	packet := encodeArtNetHeader(artNetOpPoll)
+	for {
+		a.lock.RLock()
+		destinations := slices.Clone(a.destinations)
+		a.lock.RUnlock()
+		for _, destination := range destinations {
+			if _, err := a.conn.WriteToUDP(packet, &net.UDPAddr{IP: destination, Port: ARTNET_PORT}); err != nil {
+				log.Printf("Art-Net poll error: %v\n", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-a.done:
+			return
+		}
+	}
+}
+
+func (a *ArtNetDiscovery) receive() {
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-a.done:
+				return
+			default:
+				log.Printf("Art-Net receive error: %v\n", err)
+				continue
+			}
+		}
+		if node, ok := parseArtPollReply(buf[:n]); ok {
+			node.source = addr.IP.String()
+			a.lock.Lock()
+			if _, known := a.nodes[node.IP]; !known {
+				log.Printf("Discovered Art-Net node %s (%s)\n", node.IP, node.LongName)
+			}
+			a.nodes[node.IP] = node
+			a.lock.Unlock()
+		}
+	}
+}
+
+// Nodes returns the nodes that replied to destination, every node for a broadcast destination.
+func (a *ArtNetDiscovery) Nodes(destination net.IP) []ArtNetNode {
+	broadcast := destination.Equal(net.IPv4bcast) || destination.To4() != nil && destination.To4()[3] == 255
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	nodes := make([]ArtNetNode, 0, len(a.nodes))
+	for _, node := range a.nodes {
+		if broadcast || node.source == destination.String() {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (a *ArtNetDiscovery) Close() error {
+	close(a.done)
+	return a.conn.Close()
+}
+
+// ArtNetDriver sends pixel data as ArtDmx packets, one universe per 170 RGB pixels.
+type ArtNetDriver struct {
+	conn        *net.UDPConn
+	destination *net.UDPAddr
+	portAddress uint16 // 15 bit Port-Address (net, subnet, universe) of the first universe.
+	slots       int    // Slots used per universe.
+	sync        bool
+	sequence    uint8
+	packet      []byte
+	discovery   *ArtNetDiscovery // Nil without discovery.
+}
+
+// NewArtNetDriver fills slots channels of every universe before moving to the next one.
+// With discovery, the destination is polled for nodes.
+func NewArtNetDriver(address string, slots int, opts config.ArtNetConfig, discovery *ArtNetDiscovery) (*ArtNetDriver, error) {
+	destination, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	if discovery != nil {
+		discovery.add(destination.IP)
+	}
+	return &ArtNetDriver{
+		conn:        conn,
+		destination: destination,
+		portAddress: uint16(opts.Net)<<8 | uint16(opts.Subnet)<<4 | uint16(opts.Universe),
+		slots:       slots,
+		sync:        opts.Sync,
+		packet:      make([]byte, artNetHeaderLength+512),
+		discovery:   discovery,
+	}, nil
+}
+
+func (d *ArtNetDriver) Write(data []byte) error {
+	// Sequence 0 disables reordering on the node, so it wraps from 255 to 1.
+	d.sequence = d.sequence%255 + 1
+
+	for start := 0; start < len(data); start += d.slots {
+		end := min(start+d.slots, len(data))
+		portAddress := d.portAddress + uint16(start/d.slots)
+		if _, err := d.conn.WriteToUDP(d.encodeDmx(portAddress, data[start:end]), d.destination); err != nil {
+			return err
+		}
+	}
+	if d.sync {
+		if _, err := d.conn.WriteToUDP(encodeArtNetHeader(artNetOpSync), d.destination); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeArtNetHeader returns the ID, OpCode and ProtVer of a packet followed by two zero bytes,
+// Flags and DiagPriority of ArtPoll or Aux1 and Aux2 of ArtSync.
+func encodeArtNetHeader(opCode uint16) []byte {
+	p := make([]byte, 14)
+	copy(p, artNetID[:])
+	binary.LittleEndian.PutUint16(p[8:], opCode)
+	binary.BigEndian.PutUint16(p[10:], artNetProtocolVersion)
+	return p
+}
+
+// encodeDmx builds an ArtDmx packet for slots in the reusable packet buffer.
+func (d *ArtNetDriver) encodeDmx(portAddress uint16, slots []byte) []byte {
+	// The DMX length must be even.
+	length := len(slots) + len(slots)%2
+	p := d.packet[:artNetHeaderLength+length]
+	copy(p, artNetID[:])
+	binary.LittleEndian.PutUint16(p[8:], artNetOpDmx)
+	binary.BigEndian.PutUint16(p[10:], artNetProtocolVersion)
+	p[12] = d.sequence
+	p[13] = 0                      // Physical
+	p[14] = byte(portAddress)      // SubUni
+	p[15] = byte(portAddress >> 8) // Net
+	binary.BigEndian.PutUint16(p[16:], uint16(length))
+	copy(p[artNetHeaderLength:], slots)
+	if length != len(slots) {
+		p[len(p)-1] = 0
+	}
+	return p
+}
+
+func parseArtPollReply(p []byte) (ArtNetNode, bool) {
+	if len(p) < 108 || !bytes.Equal(p[:8], artNetID[:]) || binary.LittleEndian.Uint16(p[8:]) != artNetOpPollReply {
+		return ArtNetNode{}, false
+	}
+	return ArtNetNode{
+		IP:        net.IP(p[10:14]).String(),
+		ShortName: string(bytes.TrimRight(p[26:44], "\x00")),
+		LongName:  string(bytes.TrimRight(p[44:108], "\x00")),
+		LastSeen:  time.Now(),
+	}, true
+}
+
+// Nodes returns the nodes of the destination discovered through ArtPoll.
+func (d *ArtNetDriver) Nodes() []ArtNetNode {
+	if d.discovery == nil {
+		return nil
+	}
+	return d.discovery.Nodes(d.destination.IP)
+}
+
+func (d *ArtNetDriver) Close() error {
+	return d.conn.Close()
+}
@@ -0,0 +1,137 @@
+package output_test
+
+import (
+	"bytes"
+	"ddp-sender/config"
+	"ddp-sender/output"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func readArtNetPacket(t *testing.T, conn *net.UDPConn) (opCode uint16, packet []byte) {
+	t.Helper()
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("Failed to read Art-Net packet: %v", err)
+	}
+	if !bytes.Equal(buf[:8], []byte("Art-Net\x00")) {
+		t.Fatalf("Invalid Art-Net ID: %q", buf[:8])
+	}
+	return binary.LittleEndian.Uint16(buf[8:]), buf[:n]
+}
+
+func TestArtNetDriver_DmxAndSync(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP: %v", err)
+	}
+	defer conn.Close()
+
+	driver, err := output.NewArtNetDriver(conn.LocalAddr().String(), 510, config.ArtNetConfig{
+		Net:      1,
+		Subnet:   2,
+		Universe: 15,
+		Sync:     true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewArtNetDriver() error = %v", err)
+	}
+	defer driver.Close()
+
+	// 171 pixels: a full universe and a single pixel (odd length, padded) in the next one.
+	frame := make([]byte, 171*3)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+	if err := driver.Write(frame); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	expected := []struct {
+		subUni byte
+		net    byte
+		data   []byte
+	}{
+		{0x2f, 1, frame[:510]},
+		{0x30, 1, append(append([]byte{}, frame[510:]...), 0)}, // Port-Address carries into the next subnet.
+	}
+	for i, want := range expected {
+		opCode, packet := readArtNetPacket(t, conn)
+		if opCode != 0x5000 {
+			t.Fatalf("Packet %d OpCode = %#x, want ArtDmx", i, opCode)
+		}
+		if packet[12] != 1 {
+			t.Errorf("Packet %d sequence = %d, want 1", i, packet[12])
+		}
+		if packet[14] != want.subUni || packet[15] != want.net {
+			t.Errorf("Packet %d Port-Address = %d/%#x, want %d/%#x", i, packet[15], packet[14], want.net, want.subUni)
+		}
+		if length := binary.BigEndian.Uint16(packet[16:]); int(length) != len(want.data) {
+			t.Errorf("Packet %d length = %d, want %d", i, length, len(want.data))
+		}
+		if !bytes.Equal(packet[18:], want.data) {
+			t.Errorf("Packet %d data does not match the frame", i)
+		}
+	}
+
+	if opCode, _ := readArtNetPacket(t, conn); opCode != 0x5200 {
+		t.Errorf("OpCode = %#x, want ArtSync", opCode)
+	}
+}
+
+func TestArtNetDriver_HandlesPollReply(t *testing.T) {
+	discovery, err := output.NewArtNetDiscovery()
+	if err != nil {
+		t.Skipf("Art-Net port unavailable: %v", err)
+	}
+	defer discovery.Close()
+
+	// Drivers share the discovery socket, only one can bind the Art-Net port.
+	var drivers []*output.ArtNetDriver
+	for _, address := range []string{"127.0.0.1:6454", "127.0.0.1:6455", "10.0.0.8:6454"} {
+		driver, err := output.NewArtNetDriver(address, 510, config.ArtNetConfig{Discover: true}, discovery)
+		if err != nil {
+			t.Fatalf("NewArtNetDriver(%s) error = %v", address, err)
+		}
+		defer driver.Close()
+		drivers = append(drivers, driver)
+	}
+
+	node, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: output.ARTNET_PORT})
+	if err != nil {
+		t.Fatalf("Failed to dial UDP: %v", err)
+	}
+	defer node.Close()
+
+	reply := make([]byte, 239)
+	copy(reply, "Art-Net\x00")
+	binary.LittleEndian.PutUint16(reply[8:], 0x2100)
+	copy(reply[10:14], []byte{10, 0, 0, 7})
+	copy(reply[26:], "Node")
+	copy(reply[44:], "Test node")
+	if _, err := node.Write(reply); err != nil {
+		t.Fatalf("Failed to send ArtPollReply: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(drivers[0].Nodes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("ArtPollReply was not handled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// The reply came from 127.0.0.1, the destination of the first two drivers.
+	for i, driver := range drivers[:2] {
+		nodes := driver.Nodes()
+		if len(nodes) != 1 || nodes[0].IP != "10.0.0.7" || nodes[0].ShortName != "Node" || nodes[0].LongName != "Test node" {
+			t.Errorf("Driver %d Nodes() = %+v", i, nodes)
+		}
+	}
+	if nodes := drivers[2].Nodes(); len(nodes) != 0 {
+		t.Errorf("Nodes() of another destination = %+v, want none", nodes)
+	}
+}
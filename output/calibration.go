@@ -0,0 +1,64 @@
+package output
+
+import (
+	"ddp-sender/config"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Calibration corrects composed colors for the LEDs of an output.
+type Calibration struct {
+	gain  [3]float64
+	gamma [3]float64
+	lut   *[3][256]float64 // Output value (0-1) per 8-bit input, nil without a table.
+}
+
+// NewCalibration prepares profile, which must have been validated by the config.
+func NewCalibration(profile config.CalibrationProfile) *Calibration {
+	c := &Calibration{gain: [3]float64{1, 1, 1}, gamma: [3]float64{1, 1, 1}}
+	if profile.Gain != nil {
+		c.gain = [3]float64{profile.Gain.R, profile.Gain.G, profile.Gain.B}
+	}
+	if profile.Gamma != nil {
+		c.gamma = [3]float64{profile.Gamma.R, profile.Gamma.G, profile.Gamma.B}
+	}
+	if white, err := colorful.Hex(profile.WhitePoint); profile.WhitePoint != "" && err == nil {
+		c.gain[0] *= white.R
+		c.gain[1] *= white.G
+		c.gain[2] *= white.B
+	}
+	if profile.LUT != nil {
+		c.lut = &[3][256]float64{}
+		for channel, table := range [][]int{profile.LUT.R, profile.LUT.G, profile.LUT.B} {
+			for i, value := range table {
+				c.lut[channel][i] = float64(value) / 255
+			}
+		}
+	}
+	return c
+}
+
+// Apply returns the corrected channels of color, between 0 and 1.
+func (c *Calibration) Apply(color colorful.Color) (r, g, b float64) {
+	return c.channel(0, color.R), c.channel(1, color.G), c.channel(2, color.B)
+}
+
+func (c *Calibration) channel(channel int, value float64) float64 {
+	value = math.Max(0, math.Min(1, value))
+	if c.gamma[channel] != 1 {
+		value = math.Pow(value, c.gamma[channel])
+	}
+	value = math.Min(1, value*c.gain[channel])
+	if c.lut != nil {
+		// Interpolate between the table entries around value.
+		position := value * 255
+		index := int(position)
+		if index >= 255 {
+			return c.lut[channel][255]
+		}
+		fraction := position - float64(index)
+		value = c.lut[channel][index]*(1-fraction) + c.lut[channel][index+1]*fraction
+	}
+	return value
+}
@@ -0,0 +1,55 @@
+package output_test
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/output"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestCalibration_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile config.CalibrationProfile
+		color   colorful.Color
+		expect  [3]float64
+	}{
+		{"None", config.CalibrationProfile{}, colorful.Color{R: 0.2, G: 0.5, B: 1}, [3]float64{0.2, 0.5, 1}},
+		{"Gain", config.CalibrationProfile{Gain: &config.RGBValues{R: 1, G: 0.5, B: 2}}, colorful.Color{R: 1, G: 1, B: 0.25}, [3]float64{1, 0.5, 0.5}},
+		{"GainClamped", config.CalibrationProfile{Gain: &config.RGBValues{R: 2, G: 2, B: 2}}, colorful.Color{R: 0.75, G: 0, B: 0}, [3]float64{1, 0, 0}},
+		{"Gamma", config.CalibrationProfile{Gamma: &config.RGBValues{R: 2, G: 1, B: 0.5}}, colorful.Color{R: 0.5, G: 0.5, B: 0.25}, [3]float64{0.25, 0.5, 0.5}},
+		{"WhitePoint", config.CalibrationProfile{WhitePoint: "#ff8000"}, colorful.Color{R: 1, G: 1, B: 1}, [3]float64{1, 128.0 / 255, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, g, b := output.NewCalibration(tt.profile).Apply(tt.color)
+			for i, got := range []float64{r, g, b} {
+				if math.Abs(got-tt.expect[i]) > 1e-9 {
+					t.Errorf("Apply() = %v, want %v", [3]float64{r, g, b}, tt.expect)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCalibration_LegacyMatchesOriginalCorrection(t *testing.T) {
+	legacy := output.NewCalibration(config.BuiltinCalibrations()[config.CALIBRATION_LEGACY])
+	for _, value := range []uint8{0, 3, 5, 6, 15, 16, 100, 255} {
+		r, g, b := legacy.Apply(gray(value))
+		got := [3]uint8{uint8(r*255 + 0.5), uint8(g*255 + 0.5), uint8(b*255 + 0.5)}
+
+		expect := [3]uint8{value, uint8(float64(value) * 0.75), uint8(float64(value) * 0.53)}
+		if value > 15 {
+			expect = [3]uint8{value, uint8(float64(value) * 0.43), uint8(float64(value) * 0.3)}
+		} else if value > 5 {
+			expect = [3]uint8{value, uint8(float64(value) * 0.6), uint8(float64(value) * 0.45)}
+		}
+		if got != expect {
+			t.Errorf("Legacy(%d) = %v, want %v", value, got, expect)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"net"
+
+	"github.com/coral/ddp"
+)
+
+// DDPDriver sends pixel data to a DDP controller, splitting frames larger than one packet.
+type DDPDriver struct {
+	conn   *net.UDPConn
+	header ddp.DDPHeader
+	offset int // Byte offset of the first pixel on the controller.
+	packet []byte
+}
+
+func NewDDPDriver(address string, offset int, format *PixelFormat) (*DDPDriver, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	header := ddp.DefaultDDPHeader()
+	header.DataType = format.DDPDataType()
+	return &DDPDriver{
+		conn:   conn,
+		header: header,
+		offset: format.PixelSize() * offset,
+		packet: make([]byte, 0, 10+ddp.DDP_MAX_DATALEN),
+	}, nil
+}
+
+func (d *DDPDriver) Write(data []byte) error {
+	start := 0
+	for {
+		end := min(start+ddp.DDP_MAX_DATALEN, len(data))
+
+		// Only the last packet of a frame asks the controller to display it.
+		d.header.F1.Push = end == len(data)
+		d.header.Offset = uint32(d.offset + start)
+		d.header.Length = uint16(end - start)
+		d.header.SequenceNumber = d.header.SequenceNumber%15 + 1
+
+		d.packet = append(append(d.packet[:0], d.header.Bytes()...), data[start:end]...)
+		if _, err := d.conn.Write(d.packet); err != nil {
+			return err
+		}
+		if end == len(data) {
+			return nil
+		}
+		start = end
+	}
+}
+
+func (d *DDPDriver) Close() error {
+	return d.conn.Close()
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Encoder converts the colors of a segment to the bytes sent to its controller.
+type Encoder struct {
+	Calibration *Calibration
+	Format      *PixelFormat
+	Limiter     *PowerLimiter // Optional.
+	Dither      *Dither       // Optional.
+}
+
+// Encode returns the calibrated channels of colors in the pixel format, limited to the power budget.
+// Frames scaled down by the limiter are quantized down, rounding up could go over budget again.
+func (e *Encoder) Encode(colors []colorful.Color) ([]byte, PowerEstimate) {
+	levels := make([]float64, 0, e.Format.Channels()*len(colors))
+	for _, color := range colors {
+		r, g, b := e.Calibration.Apply(color)
+		levels = e.Format.appendLevels(levels, r, g, b)
+	}
+
+	power := PowerEstimate{Scale: 1}
+	if e.Limiter != nil {
+		power = e.Limiter.Limit(levels)
+	}
+
+	data := make([]byte, 0, e.Format.PixelSize()*len(colors))
+	maxValue := e.Format.MaxValue()
+	limited := power.Scale < 1
+	for i, level := range levels {
+		value := level * maxValue
+		switch {
+		case limited:
+			value = math.Floor(value)
+		case e.Dither != nil:
+			value = e.Dither.quantize(i, value)
+		default:
+			value = math.Floor(value + 0.5)
+		}
+		data = e.Format.appendValue(data, value)
+		levels[i] = value / maxValue
+	}
+	if limited {
+		power.LimitedMilliamps = e.Limiter.Estimate(levels)
+	}
+	return data, power
+}
+
+// Dither carries the part of every channel lost to quantization over to the next frame,
+// so over a few frames the average output matches levels between two steps.
+type Dither struct {
+	residuals []float64
+}
+
+// quantize returns value rounded to a step after adding the residual of channel i.
+func (d *Dither) quantize(i int, value float64) float64 {
+	if i >= len(d.residuals) {
+		d.residuals = append(d.residuals, make([]float64, i+1-len(d.residuals))...)
+	}
+	if value <= 0 {
+		// Black stays black, without flickering leftovers.
+		d.residuals[i] = 0
+		return 0
+	}
+	value += d.residuals[i]
+	step := math.Floor(value + 0.5)
+	d.residuals[i] = value - step
+	return step
+}
@@ -0,0 +1,72 @@
+package output_test
+
+import (
+	"bytes"
+	"ddp-sender/config"
+	"ddp-sender/output"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestEncoder_DitherAveragesBetweenSteps(t *testing.T) {
+	encoder := &output.Encoder{Calibration: uncalibrated, Format: rgb, Dither: &output.Dither{}}
+	// A quarter of the first step, the tail of a slow decay.
+	color := colorful.Color{R: 0.25 / 255}
+
+	sum := 0
+	for i := 0; i < 100; i++ {
+		data, _ := encoder.Encode([]colorful.Color{color})
+		sum += int(data[0])
+	}
+	if sum != 25 {
+		t.Errorf("Sum of 100 dithered frames = %d, want 25", sum)
+	}
+
+	// Without dithering the level is always rounded to the same step.
+	plain := &output.Encoder{Calibration: uncalibrated, Format: rgb}
+	if data, _ := plain.Encode([]colorful.Color{color}); data[0] != 0 {
+		t.Errorf("Undithered value = %d, want 0", data[0])
+	}
+
+	// Black resets the carried error.
+	data, _ := encoder.Encode([]colorful.Color{{}})
+	if !bytes.Equal(data, []byte{0, 0, 0}) {
+		t.Errorf("Dithered black = %v, want black", data)
+	}
+}
+
+func TestEncoder_LimitedFramesStayWithinBudget(t *testing.T) {
+	limiter := output.NewPowerLimiter(config.PowerConfig{LimitMilliamps: 310, ChannelMilliamps: 20, IdleMilliamps: 1}, rgb)
+	encoder := &output.Encoder{Calibration: uncalibrated, Format: rgb, Limiter: limiter, Dither: &output.Dither{}}
+	colors := make([]colorful.Color, 10)
+	for i := range colors {
+		colors[i] = colorful.Color{R: 1, G: 1, B: 1}
+	}
+
+	// Scaled by 0.5, every channel is at step 127.5: rounding up to 128 would draw 311.2mA.
+	for frame := 0; frame < 4; frame++ {
+		data, power := encoder.Encode(colors)
+		if !bytes.Equal(data, bytes.Repeat([]byte{127}, 30)) {
+			t.Fatalf("Frame %d = %v, want every channel at 127", frame, data)
+		}
+		if want := 10 + 30*20*127.0/255; power.LimitedMilliamps > 310 || math.Abs(power.LimitedMilliamps-want) > 1e-9 {
+			t.Errorf("Frame %d LimitedMilliamps = %g, want %g", frame, power.LimitedMilliamps, want)
+		}
+	}
+}
+
+func TestEncoder_SixteenBit(t *testing.T) {
+	format := output.NewPixelFormat(config.PIXEL_FORMAT_GRB, 16, config.WhiteConfig{})
+	encoder := &output.Encoder{Calibration: uncalibrated, Format: format}
+
+	data, _ := encoder.Encode([]colorful.Color{{R: 1, G: 0.5, B: 0.25 / 255}})
+	expect := []byte{0x80, 0x00, 0xff, 0xff, 0x00, 0x40}
+	if !bytes.Equal(data, expect) {
+		t.Errorf("Encode() = %x, want %x", data, expect)
+	}
+	if format.PixelSize() != 6 || format.UniverseSlots() != 510 {
+		t.Errorf("PixelSize() = %d, UniverseSlots() = %d, want 6 and 510", format.PixelSize(), format.UniverseSlots())
+	}
+}
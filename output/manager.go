@@ -0,0 +1,97 @@
+package output
+
+import (
+	"ddp-sender/config"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Manager splits every frame of the LED canvas between the configured outputs.
+type Manager struct {
+	outputs   []*Output
+	discovery *ArtNetDiscovery // Shared by the Art-Net outputs with discovery, nil without.
+}
+
+func NewManager(cfg *config.Config) (*Manager, error) {
+	m := &Manager{}
+	for _, outputConfig := range cfg.ResolvedOutputs() {
+		format := NewPixelFormat(outputConfig.PixelFormat, outputConfig.BitDepth, *outputConfig.White)
+		driver, err := m.newDriver(outputConfig, format)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("output %s: %w", outputConfig.Name, err)
+		}
+		segment := Segment{
+			Start:   outputConfig.Start,
+			Length:  outputConfig.Length,
+			Reverse: outputConfig.Reverse,
+		}
+		profile, _ := cfg.Calibration(outputConfig.Calibration)
+		encoder := &Encoder{
+			Calibration: NewCalibration(profile),
+			Format:      format,
+			Limiter:     NewPowerLimiter(*outputConfig.Power, format),
+		}
+		if outputConfig.Dither {
+			encoder.Dither = &Dither{}
+		}
+		m.outputs = append(m.outputs, NewOutput(outputConfig.Name, segment, encoder, driver))
+		log.Printf("Output %s -> %s %s (LEDs %d-%d, %s, calibration %s)\n", outputConfig.Name, outputConfig.Protocol, outputConfig.Address, segment.Start, segment.Start+segment.Length-1, outputConfig.PixelFormat, outputConfig.Calibration)
+	}
+	return m, nil
+}
+
+func (m *Manager) newDriver(outputConfig config.OutputConfig, format *PixelFormat) (Driver, error) {
+	switch outputConfig.Protocol {
+	case config.PROTOCOL_DDP:
+		return NewDDPDriver(outputConfig.Address, outputConfig.Offset, format)
+	case config.PROTOCOL_SACN:
+		return NewSACNDriver(outputConfig.Address, format.UniverseSlots(), *outputConfig.SACN)
+	case config.PROTOCOL_ARTNET:
+		if outputConfig.ArtNet.Discover && m.discovery == nil {
+			discovery, err := NewArtNetDiscovery()
+			if err != nil {
+				return nil, fmt.Errorf("art-net discovery: %w", err)
+			}
+			m.discovery = discovery
+		}
+		var discovery *ArtNetDiscovery
+		if outputConfig.ArtNet.Discover {
+			discovery = m.discovery
+		}
+		return NewArtNetDriver(outputConfig.Address, format.UniverseSlots(), *outputConfig.ArtNet, discovery)
+	case config.PROTOCOL_WLED:
+		return NewWLEDDriver(outputConfig.Address, outputConfig.Offset, *outputConfig.WLED)
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q", outputConfig.Protocol)
+	}
+}
+
+// Send hands frame to every output without waiting for the writes.
+func (m *Manager) Send(frame []colorful.Color) {
+	for _, output := range m.outputs {
+		output.Send(frame)
+	}
+}
+
+func (m *Manager) Stats() []Stats {
+	stats := make([]Stats, len(m.outputs))
+	for i, output := range m.outputs {
+		stats[i] = output.Stats()
+	}
+	return stats
+}
+
+func (m *Manager) Close() error {
+	var errs []error
+	for _, output := range m.outputs {
+		errs = append(errs, output.Close())
+	}
+	if m.discovery != nil {
+		errs = append(errs, m.discovery.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,164 @@
+package output
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Driver encodes and sends a pixel buffer to a single controller.
+type Driver interface {
+	Write(data []byte) error
+	Close() error
+}
+
+// NodeDiscoverer is implemented by drivers that discover the nodes at their destination.
+type NodeDiscoverer interface {
+	Nodes() []ArtNetNode
+}
+
+// Segment is the slice of the virtual LED canvas owned by an output.
+type Segment struct {
+	Start   int  // First LED of the canvas sent to the output.
+	Length  int  // Amount of LEDs sent to the output.
+	Reverse bool // Send the LEDs in reverse order.
+}
+
+// Stats is a snapshot of the counters of an output.
+type Stats struct {
+	Name        string        `json:"name"`
+	Sent        int64         `json:"sent"`
+	Errors      int64         `json:"errors"`
+	Dropped     int64         `json:"dropped"`
+	LastError   string        `json:"lastError,omitempty"`
+	LastLatency time.Duration `json:"lastLatency"`
+	MaxLatency  time.Duration `json:"maxLatency"`
+	Power       PowerEstimate `json:"power"`
+	Nodes       []ArtNetNode  `json:"nodes,omitempty"` // Art-Net nodes discovered at the destination.
+}
+
+// Output sends its segment of every frame through its driver on its own goroutine,
+// so a slow or unreachable controller never delays the other outputs.
+type Output struct {
+	Name    string
+	segment Segment
+	encoder *Encoder
+	driver  Driver
+	frames  chan []colorful.Color
+
+	sent        atomic.Int64
+	errors      atomic.Int64
+	dropped     atomic.Int64
+	lastLatency atomic.Int64
+	maxLatency  atomic.Int64
+	lastError   atomic.Value // string
+	power       atomic.Value // PowerEstimate
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func NewOutput(name string, segment Segment, encoder *Encoder, driver Driver) *Output {
+	o := &Output{
+		Name:    name,
+		segment: segment,
+		encoder: encoder,
+		driver:  driver,
+		frames:  make(chan []colorful.Color, 1),
+		done:    make(chan struct{}),
+	}
+	go o.run()
+	return o
+}
+
+// Send queues the output segment of frame, replacing any frame that was not sent yet.
+func (o *Output) Send(frame []colorful.Color) {
+	colors := o.extract(frame)
+	select {
+	case o.frames <- colors:
+		return
+	default:
+	}
+	// Mailbox is full: the controller is behind, drop the stale frame.
+	select {
+	case <-o.frames:
+		o.dropped.Add(1)
+	default:
+	}
+	select {
+	case o.frames <- colors:
+	default:
+		o.dropped.Add(1)
+	}
+}
+
+func (o *Output) extract(frame []colorful.Color) []colorful.Color {
+	colors := make([]colorful.Color, o.segment.Length)
+	for i := range colors {
+		src := o.segment.Start + i
+		if o.segment.Reverse {
+			src = o.segment.Start + o.segment.Length - 1 - i
+		}
+		if src < len(frame) {
+			colors[i] = frame[src]
+		}
+	}
+	return colors
+}
+
+func (o *Output) run() {
+	defer close(o.done)
+	for colors := range o.frames {
+		data, power := o.encoder.Encode(colors)
+		o.power.Store(power)
+		start := time.Now()
+		err := o.driver.Write(data)
+		latency := time.Since(start)
+
+		o.lastLatency.Store(int64(latency))
+		if int64(latency) > o.maxLatency.Load() {
+			o.maxLatency.Store(int64(latency))
+		}
+		if err != nil {
+			if o.errors.Add(1) == 1 {
+				log.Printf("Output %s write error: %v\n", o.Name, err)
+			}
+			o.lastError.Store(err.Error())
+			continue
+		}
+		o.sent.Add(1)
+	}
+}
+
+func (o *Output) Stats() Stats {
+	stats := Stats{
+		Name:        o.Name,
+		Sent:        o.sent.Load(),
+		Errors:      o.errors.Load(),
+		Dropped:     o.dropped.Load(),
+		LastLatency: time.Duration(o.lastLatency.Load()),
+		MaxLatency:  time.Duration(o.maxLatency.Load()),
+	}
+	if lastError, ok := o.lastError.Load().(string); ok {
+		stats.LastError = lastError
+	}
+	if power, ok := o.power.Load().(PowerEstimate); ok {
+		stats.Power = power
+	}
+	if driver, ok := o.driver.(NodeDiscoverer); ok {
+		stats.Nodes = driver.Nodes()
+	}
+	return stats
+}
+
+// Close stops the output goroutine and closes the driver.
+func (o *Output) Close() error {
+	o.closeOnce.Do(func() {
+		close(o.frames)
+	})
+	<-o.done
+	return o.driver.Close()
+}
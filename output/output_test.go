@@ -0,0 +1,107 @@
+package output_test
+
+import (
+	"bytes"
+	"ddp-sender/config"
+	"ddp-sender/output"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+var (
+	uncalibrated = output.NewCalibration(config.CalibrationProfile{})
+	rgb          = output.NewPixelFormat(config.PIXEL_FORMAT_RGB, 8, config.WhiteConfig{})
+)
+
+func gray(value uint8) colorful.Color {
+	return colorful.Color{R: float64(value) / 255, G: float64(value) / 255, B: float64(value) / 255}
+}
+
+type recordingDriver struct {
+	sync.Mutex
+	writes [][]byte
+	block  chan struct{}
+}
+
+func (d *recordingDriver) Write(data []byte) error {
+	if d.block != nil {
+		<-d.block
+	}
+	d.Lock()
+	defer d.Unlock()
+	d.writes = append(d.writes, data)
+	return nil
+}
+
+func (d *recordingDriver) Close() error { return nil }
+
+func (d *recordingDriver) last(t *testing.T) []byte {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		d.Lock()
+		if len(d.writes) > 0 {
+			data := d.writes[len(d.writes)-1]
+			d.Unlock()
+			return data
+		}
+		d.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("No frame written")
+	return nil
+}
+
+func TestOutput_SendsSegment(t *testing.T) {
+	frame := []colorful.Color{gray(0), gray(1), gray(2), gray(3)}
+
+	tests := []struct {
+		name    string
+		segment output.Segment
+		expect  []byte
+	}{
+		{"Forward", output.Segment{Start: 1, Length: 2}, []byte{1, 1, 1, 2, 2, 2}},
+		{"Reverse", output.Segment{Start: 1, Length: 3, Reverse: true}, []byte{3, 3, 3, 2, 2, 2, 1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := &recordingDriver{}
+			o := output.NewOutput(tt.name, tt.segment, &output.Encoder{Calibration: uncalibrated, Format: rgb}, driver)
+			defer o.Close()
+
+			o.Send(frame)
+			if got := driver.last(t); !bytes.Equal(got, tt.expect) {
+				t.Errorf("Write() data = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestOutput_StalledDriverDoesNotBlock(t *testing.T) {
+	stalled := &recordingDriver{block: make(chan struct{})}
+	o := output.NewOutput("stalled", output.Segment{Start: 0, Length: 1}, &output.Encoder{Calibration: uncalibrated, Format: rgb}, stalled)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			o.Send([]colorful.Color{gray(uint8(i))})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Send() blocked on a stalled driver")
+	}
+	if o.Stats().Dropped == 0 {
+		t.Error("Expected stale frames to be dropped")
+	}
+
+	close(stalled.block)
+	o.Close()
+}
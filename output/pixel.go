@@ -0,0 +1,154 @@
+package output
+
+import (
+	"ddp-sender/config"
+	"encoding/binary"
+	"math"
+	"strings"
+
+	"github.com/coral/ddp"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// DMX_SLOTS is the amount of channels in a sACN or Art-Net universe.
+const DMX_SLOTS = 512
+
+// PixelFormat lays out calibrated colors as the channels the LEDs of an output expect.
+type PixelFormat struct {
+	order    []int // Channels of a pixel, indexes of [r, g, b, w, c].
+	bitDepth int   // Bits per channel, 8 or 16.
+	strategy string
+	warm     colorful.Color // Tint of the w LEDs.
+	cool     colorful.Color // Tint of the c LEDs.
+}
+
+// NewPixelFormat prepares format, which must have been validated by the config.
+func NewPixelFormat(format string, bitDepth int, white config.WhiteConfig) *PixelFormat {
+	f := &PixelFormat{
+		bitDepth: bitDepth,
+		strategy: white.Strategy,
+		warm:     kelvinToColor(white.Temperature),
+		cool:     kelvinToColor(white.CoolTemperature),
+	}
+	for _, channel := range format {
+		f.order = append(f.order, strings.IndexRune("rgbwc", channel))
+	}
+	return f
+}
+
+// Channels returns the amount of channels per pixel.
+func (f *PixelFormat) Channels() int {
+	return len(f.order)
+}
+
+// PixelSize returns the amount of bytes per pixel.
+func (f *PixelFormat) PixelSize() int {
+	return f.Channels() * f.bitDepth / 8
+}
+
+// MaxValue returns the value of a channel at full brightness.
+func (f *PixelFormat) MaxValue() float64 {
+	return float64(uint(1)<<f.bitDepth - 1)
+}
+
+// DDPDataType returns the DDP data type of the pixels, formats without one are sent as undefined.
+func (f *PixelFormat) DDPDataType() ddp.PixelDataType {
+	if f.bitDepth == 16 {
+		// 16-bit data is described by the size of every channel.
+		switch f.Channels() {
+		case 3:
+			return ddp.PixelDataType{DataType: ddp.RGB, DataSize: ddp.Pixel16Bits}
+		case 4:
+			return ddp.PixelDataType{DataType: ddp.RGBW, DataSize: ddp.Pixel16Bits}
+		}
+		return ddp.PixelDataType{}
+	}
+	switch f.Channels() {
+	case 3:
+		return ddp.PixelDataType{DataType: ddp.RGB, DataSize: ddp.Pixel24Bits}
+	case 4:
+		return ddp.PixelDataType{DataType: ddp.RGBW, DataSize: ddp.Pixel32Bits}
+	default:
+		return ddp.PixelDataType{}
+	}
+}
+
+// UniverseSlots returns the DMX slots used per universe, so no pixel is split between two universes.
+func (f *PixelFormat) UniverseSlots() int {
+	return DMX_SLOTS / f.PixelSize() * f.PixelSize()
+}
+
+// appendLevels appends the channel levels (0-1) of a calibrated color to dst.
+func (f *PixelFormat) appendLevels(dst []float64, r, g, b float64) []float64 {
+	channels := [5]float64{r, g, b}
+	if f.Channels() > 3 {
+		channels = f.extractWhite(r, g, b)
+	}
+	for _, channel := range f.order {
+		dst = append(dst, channels[channel])
+	}
+	return dst
+}
+
+// appendValue appends a quantized channel value (0-MaxValue) to dst, big-endian for 16-bit channels.
+func (f *PixelFormat) appendValue(dst []byte, value float64) []byte {
+	if f.bitDepth == 16 {
+		return binary.BigEndian.AppendUint16(dst, uint16(value))
+	}
+	return append(dst, uint8(value))
+}
+
+// extractWhite moves the part of the color the white LEDs can produce to the w and c channels.
+func (f *PixelFormat) extractWhite(r, g, b float64) [5]float64 {
+	channels := [5]float64{r, g, b}
+	switch f.strategy {
+	case config.WHITE_STRATEGY_MIN:
+		white := min(r, g, b)
+		channels = [5]float64{r - white, g - white, b - white, white}
+	case config.WHITE_STRATEGY_TEMPERATURE:
+		// The w LEDs take as much of the color as their tint allows, the c LEDs take from what remains.
+		for i, tint := range []colorful.Color{f.warm, f.cool} {
+			if i == 1 && f.Channels() < 5 {
+				break
+			}
+			white := 1.0
+			for channel, amount := range [3]float64{tint.R, tint.G, tint.B} {
+				if amount > 0 {
+					white = math.Min(white, channels[channel]/amount)
+				}
+			}
+			white = math.Max(0, white)
+			channels[0] -= white * tint.R
+			channels[1] -= white * tint.G
+			channels[2] -= white * tint.B
+			channels[3+i] = white
+		}
+	}
+	return channels
+}
+
+// kelvinToColor approximates the tint of a white LED of the given color temperature,
+// scaled so its strongest channel is 1 (Tanner Helland's fit of the blackbody curve).
+func kelvinToColor(kelvin int) colorful.Color {
+	temperature := float64(kelvin) / 100
+	var r, g, b float64
+	if temperature <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(temperature) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(temperature-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(temperature-60, -0.0755148492)
+	}
+	switch {
+	case temperature >= 66:
+		b = 255
+	case temperature <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(temperature-10) - 305.0447927307
+	}
+	clamp := func(value float64) float64 { return math.Max(0, math.Min(255, value)) }
+	r, g, b = clamp(r), clamp(g), clamp(b)
+	peak := math.Max(r, math.Max(g, b))
+	return colorful.Color{R: r / peak, G: g / peak, B: b / peak}
+}
@@ -0,0 +1,77 @@
+package output_test
+
+import (
+	"bytes"
+	"ddp-sender/config"
+	"ddp-sender/output"
+	"testing"
+
+	"github.com/coral/ddp"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestPixelFormat_Encodes(t *testing.T) {
+	color := colorful.Color{R: 1, G: 0.5, B: 0.25}
+
+	tests := []struct {
+		name   string
+		format string
+		white  config.WhiteConfig
+		expect []byte
+	}{
+		{"RGB", "rgb", config.WhiteConfig{}, []byte{255, 128, 64}},
+		{"GRB", "grb", config.WhiteConfig{}, []byte{128, 255, 64}},
+		{"BRG", "brg", config.WhiteConfig{}, []byte{64, 255, 128}},
+		{"RGBWNone", "rgbw", config.WhiteConfig{Strategy: config.WHITE_STRATEGY_NONE}, []byte{255, 128, 64, 0}},
+		{"RGBWMin", "rgbw", config.WhiteConfig{Strategy: config.WHITE_STRATEGY_MIN}, []byte{191, 64, 0, 64}},
+		{"GRBWMin", "grbw", config.WhiteConfig{Strategy: config.WHITE_STRATEGY_MIN}, []byte{64, 191, 0, 64}},
+		// 6600K is neutral white, so it extracts as much as min.
+		{"RGBWTemperature", "rgbw", config.WhiteConfig{Strategy: config.WHITE_STRATEGY_TEMPERATURE, Temperature: 6600}, []byte{191, 64, 0, 64}},
+		{"RGBWCTemperature", "rgbwc", config.WhiteConfig{Strategy: config.WHITE_STRATEGY_TEMPERATURE, Temperature: 6600, CoolTemperature: 9000}, []byte{191, 64, 0, 64, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := &recordingDriver{}
+			o := output.NewOutput(tt.name, output.Segment{Length: 1}, &output.Encoder{Calibration: uncalibrated, Format: output.NewPixelFormat(tt.format, 8, tt.white)}, driver)
+			defer o.Close()
+
+			o.Send([]colorful.Color{color})
+			if got := driver.last(t); !bytes.Equal(got, tt.expect) {
+				t.Errorf("Write() data = %v, want %v", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestPixelFormat_WarmWhiteKeepsBlue(t *testing.T) {
+	format := output.NewPixelFormat("rgbw", 8, config.WhiteConfig{Strategy: config.WHITE_STRATEGY_TEMPERATURE, Temperature: 3000})
+	driver := &recordingDriver{}
+	o := output.NewOutput("warm", output.Segment{Length: 1}, &output.Encoder{Calibration: uncalibrated, Format: format}, driver)
+	defer o.Close()
+
+	o.Send([]colorful.Color{{R: 1, G: 1, B: 1}})
+	got := driver.last(t)
+	// Warm white LEDs lack blue, so the blue LED has to make up for it.
+	if got[0] != 0 || got[2] == 0 || got[3] != 255 {
+		t.Errorf("Write() data = %v, want full white with blue added by the blue LED", got)
+	}
+}
+
+func TestPixelFormat_DDPDataType(t *testing.T) {
+	tests := []struct {
+		format string
+		expect ddp.PixelDataType
+	}{
+		{"grb", ddp.PixelDataType{DataType: ddp.RGB, DataSize: ddp.Pixel24Bits}},
+		{"rgbw", ddp.PixelDataType{DataType: ddp.RGBW, DataSize: ddp.Pixel32Bits}},
+		{"rgbwc", ddp.PixelDataType{}},
+	}
+
+	for _, tt := range tests {
+		format := output.NewPixelFormat(tt.format, 8, config.WhiteConfig{})
+		if got := format.DDPDataType(); got != tt.expect {
+			t.Errorf("%s DDPDataType() = %+v, want %+v", tt.format, got, tt.expect)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package output
+
+import "ddp-sender/config"
+
+// PowerEstimate is the current drawn by the last frame of an output.
+type PowerEstimate struct {
+	Milliamps        float64 `json:"milliamps"`        // Estimated draw of the frame as composed.
+	LimitedMilliamps float64 `json:"limitedMilliamps"` // Estimated draw of the frame sent.
+	Scale            float64 `json:"scale"`            // Brightness factor applied, 1 when within budget.
+}
+
+// PowerLimiter estimates the draw of the channel levels of a frame and scales them down when it goes over budget.
+type PowerLimiter struct {
+	limit   float64   // 0 disables limiting.
+	idle    float64   // Per pixel.
+	weights []float64 // Milliamps at full brightness of every channel of a pixel.
+}
+
+func NewPowerLimiter(power config.PowerConfig, format *PixelFormat) *PowerLimiter {
+	l := &PowerLimiter{limit: power.LimitMilliamps, idle: power.IdleMilliamps}
+	for _, channel := range format.order {
+		milliamps := power.ChannelMilliamps
+		if channel > 2 {
+			milliamps = power.WhiteMilliamps
+		}
+		l.weights = append(l.weights, milliamps)
+	}
+	return l
+}
+
+// Estimate returns the draw of levels (0-1 per channel) in milliamps.
+func (l *PowerLimiter) Estimate(levels []float64) float64 {
+	pixels := len(levels) / len(l.weights)
+	milliamps := l.idle * float64(pixels)
+	for i, level := range levels {
+		milliamps += level * l.weights[i%len(l.weights)]
+	}
+	return milliamps
+}
+
+// Limit scales levels in place so their estimated draw stays within the limit.
+func (l *PowerLimiter) Limit(levels []float64) PowerEstimate {
+	milliamps := l.Estimate(levels)
+	estimate := PowerEstimate{Milliamps: milliamps, LimitedMilliamps: milliamps, Scale: 1}
+	if l.limit <= 0 || milliamps <= l.limit {
+		return estimate
+	}
+
+	// Idle draw does not depend on brightness, only scale the rest.
+	idle := l.idle * float64(len(levels)/len(l.weights))
+	estimate.Scale = max(0, l.limit-idle) / (milliamps - idle)
+	for i := range levels {
+		levels[i] *= estimate.Scale
+	}
+	estimate.LimitedMilliamps = l.Estimate(levels)
+	return estimate
+}
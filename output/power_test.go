@@ -0,0 +1,83 @@
+package output_test
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/output"
+	"math"
+	"slices"
+	"testing"
+)
+
+func uniform(level float64, length int) []float64 {
+	levels := make([]float64, length)
+	for i := range levels {
+		levels[i] = level
+	}
+	return levels
+}
+
+func fullWhite(pixels, channels int) []float64 {
+	return uniform(1, pixels*channels)
+}
+
+func TestPowerLimiter_Estimate(t *testing.T) {
+	power := config.PowerConfig{ChannelMilliamps: 20, WhiteMilliamps: 40, IdleMilliamps: 1}
+
+	tests := []struct {
+		name   string
+		format string
+		levels []float64
+		expect float64
+	}{
+		{"Black", "rgb", make([]float64, 30), 10},
+		{"FullWhite", "rgb", fullWhite(10, 3), 10 + 10*3*20},
+		{"FifthRed", "rgb", []float64{0.2, 0, 0, 0.2, 0, 0}, 2 + 2*4},
+		{"WhiteChannel", "rgbw", []float64{0, 0, 0, 1}, 1 + 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := output.NewPowerLimiter(power, output.NewPixelFormat(tt.format, 8, config.WhiteConfig{}))
+			if got := limiter.Estimate(tt.levels); math.Abs(got-tt.expect) > 1e-9 {
+				t.Errorf("Estimate() = %g, want %g", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestPowerLimiter_Limit(t *testing.T) {
+	format := output.NewPixelFormat("rgb", 8, config.WhiteConfig{})
+
+	t.Run("WithinBudget", func(t *testing.T) {
+		limiter := output.NewPowerLimiter(config.PowerConfig{LimitMilliamps: 1000, ChannelMilliamps: 20, IdleMilliamps: 1}, format)
+		levels := fullWhite(10, 3)
+		estimate := limiter.Limit(levels)
+		if estimate.Scale != 1 || !slices.Equal(levels, fullWhite(10, 3)) {
+			t.Errorf("Limit() = %+v, want the frame untouched", estimate)
+		}
+	})
+
+	t.Run("OverBudget", func(t *testing.T) {
+		limiter := output.NewPowerLimiter(config.PowerConfig{LimitMilliamps: 310, ChannelMilliamps: 20, IdleMilliamps: 1}, format)
+		levels := fullWhite(10, 3)
+		estimate := limiter.Limit(levels)
+
+		// 610mA with 10mA idle draw: the remaining 600mA have to fit in 300mA.
+		if estimate.Milliamps != 610 || estimate.Scale != 0.5 {
+			t.Errorf("Limit() = %+v, want 610mA scaled by 0.5", estimate)
+		}
+		if !slices.Equal(levels, uniform(0.5, 30)) {
+			t.Errorf("Limited levels = %v, want every channel at 0.5", levels)
+		}
+		if estimate.LimitedMilliamps > 310 || estimate.LimitedMilliamps != limiter.Estimate(levels) {
+			t.Errorf("LimitedMilliamps = %g, want the estimate of the limited frame within 310mA", estimate.LimitedMilliamps)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		limiter := output.NewPowerLimiter(config.PowerConfig{ChannelMilliamps: 20, IdleMilliamps: 1}, format)
+		if estimate := limiter.Limit(fullWhite(100, 3)); estimate.Scale != 1 || estimate.Milliamps != 6100 {
+			t.Errorf("Limit() = %+v, want the estimate without scaling", estimate)
+		}
+	})
+}
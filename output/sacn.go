@@ -0,0 +1,131 @@
+package output
+
+import (
+	"crypto/rand"
+	"ddp-sender/config"
+	"encoding/binary"
+	"net"
+)
+
+const (
+	SACN_PORT             = 5568
+	sacnHeaderLength      = 126
+	sacnVectorRootData    = 0x00000004
+	sacnVectorFramingData = 0x00000002
+	sacnVectorDMPSetProp  = 0x02
+)
+
+var sacnPacketIdentifier = [12]byte{'A', 'S', 'C', '-', 'E', '1', '.', '1', '7', 0, 0, 0}
+
+// SACNDriver sends pixel data as E1.31 (sACN) data packets, one universe per 170 RGB pixels.
+type SACNDriver struct {
+	conn        *net.UDPConn
+	destination *net.UDPAddr // nil when sending to the universe multicast groups.
+	universe    uint16
+	slots       int // Slots used per universe.
+	priority    uint8
+	sourceName  string
+	cid         [16]byte
+	sequences   []uint8 // Sequence number per universe of the segment.
+	packet      []byte
+}
+
+// NewSACNDriver fills slots channels of every universe before moving to the next one.
+func NewSACNDriver(address string, slots int, opts config.SACNConfig) (*SACNDriver, error) {
+	d := &SACNDriver{
+		universe:   uint16(opts.Universe),
+		slots:      slots,
+		priority:   uint8(*opts.Priority),
+		sourceName: opts.SourceName,
+		packet:     make([]byte, sacnHeaderLength+512),
+	}
+	if _, err := rand.Read(d.cid[:]); err != nil {
+		return nil, err
+	}
+	if !opts.Multicast {
+		destination, err := net.ResolveUDPAddr("udp4", address)
+		if err != nil {
+			return nil, err
+		}
+		d.destination = destination
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	d.conn = conn
+	return d, nil
+}
+
+// SACNMulticastAddr returns the multicast group of an E1.31 universe.
+func SACNMulticastAddr(universe uint16) *net.UDPAddr {
+	return &net.UDPAddr{
+		IP:   net.IPv4(239, 255, byte(universe>>8), byte(universe)),
+		Port: SACN_PORT,
+	}
+}
+
+func (d *SACNDriver) Write(data []byte) error {
+	universes := (len(data) + d.slots - 1) / d.slots
+	for len(d.sequences) < universes {
+		d.sequences = append(d.sequences, 0)
+	}
+
+	for i := 0; i < universes; i++ {
+		start := i * d.slots
+		end := min(start+d.slots, len(data))
+		universe := d.universe + uint16(i)
+
+		d.sequences[i]++
+		packet := d.encode(universe, d.sequences[i], data[start:end])
+
+		destination := d.destination
+		if destination == nil {
+			destination = SACNMulticastAddr(universe)
+		}
+		if _, err := d.conn.WriteToUDP(packet, destination); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode builds an E1.31 data packet for slots in the reusable packet buffer.
+func (d *SACNDriver) encode(universe uint16, sequence uint8, slots []byte) []byte {
+	length := sacnHeaderLength + len(slots)
+	p := d.packet[:length]
+	clear(p[:sacnHeaderLength])
+
+	// Root layer
+	binary.BigEndian.PutUint16(p[0:], 0x0010) // Preamble size
+	binary.BigEndian.PutUint16(p[2:], 0x0000) // Post-amble size
+	copy(p[4:16], sacnPacketIdentifier[:])
+	binary.BigEndian.PutUint16(p[16:], 0x7000|uint16(length-16))
+	binary.BigEndian.PutUint32(p[18:], sacnVectorRootData)
+	copy(p[22:38], d.cid[:])
+
+	// Framing layer
+	binary.BigEndian.PutUint16(p[38:], 0x7000|uint16(length-38))
+	binary.BigEndian.PutUint32(p[40:], sacnVectorFramingData)
+	copy(p[44:107], d.sourceName) // 64 bytes, last one always null.
+	p[108] = d.priority
+	binary.BigEndian.PutUint16(p[109:], 0) // Synchronization address
+	p[111] = sequence
+	p[112] = 0 // Options
+	binary.BigEndian.PutUint16(p[113:], universe)
+
+	// DMP layer
+	binary.BigEndian.PutUint16(p[115:], 0x7000|uint16(length-115))
+	p[117] = sacnVectorDMPSetProp
+	p[118] = 0xa1                                             // Address & data type
+	binary.BigEndian.PutUint16(p[119:], 0x0000)               // First property address
+	binary.BigEndian.PutUint16(p[121:], 0x0001)               // Address increment
+	binary.BigEndian.PutUint16(p[123:], uint16(len(slots)+1)) // Property value count
+	p[125] = 0x00                                             // DMX start code
+	copy(p[sacnHeaderLength:], slots)
+	return p
+}
+
+func (d *SACNDriver) Close() error {
+	return d.conn.Close()
+}
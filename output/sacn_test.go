@@ -0,0 +1,97 @@
+package output_test
+
+import (
+	"bytes"
+	"ddp-sender/config"
+	"ddp-sender/output"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+type sacnPacket struct {
+	priority uint8
+	sequence uint8
+	universe uint16
+	data     []byte
+}
+
+func readSACNPacket(t *testing.T, conn *net.UDPConn) sacnPacket {
+	t.Helper()
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("Failed to read sACN packet: %v", err)
+	}
+	p := buf[:n]
+	if !bytes.Equal(p[4:16], []byte("ASC-E1.17\x00\x00\x00")) {
+		t.Fatalf("Invalid ACN packet identifier: %q", p[4:16])
+	}
+	if flagsLength := binary.BigEndian.Uint16(p[16:]); int(flagsLength&0x0fff) != n-16 {
+		t.Errorf("Root layer length = %d, want %d", flagsLength&0x0fff, n-16)
+	}
+	if vector := binary.BigEndian.Uint32(p[18:]); vector != 4 {
+		t.Errorf("Root vector = %d, want 4", vector)
+	}
+	if name := string(bytes.TrimRight(p[44:108], "\x00")); name != "test-source" {
+		t.Errorf("Source name = %q, want test-source", name)
+	}
+	count := int(binary.BigEndian.Uint16(p[123:]))
+	if count != n-125 {
+		t.Errorf("Property value count = %d, want %d", count, n-125)
+	}
+	return sacnPacket{
+		priority: p[108],
+		sequence: p[111],
+		universe: binary.BigEndian.Uint16(p[113:]),
+		data:     p[126:],
+	}
+}
+
+func TestSACNDriver_SplitsUniverses(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP: %v", err)
+	}
+	defer conn.Close()
+
+	priority := 150
+	driver, err := output.NewSACNDriver(conn.LocalAddr().String(), 510, config.SACNConfig{
+		Universe:   5,
+		Priority:   &priority,
+		SourceName: "test-source",
+	})
+	if err != nil {
+		t.Fatalf("NewSACNDriver() error = %v", err)
+	}
+	defer driver.Close()
+
+	// 200 pixels span a full universe (170 pixels) and 30 pixels of the next one.
+	frame := make([]byte, 200*3)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+
+	for sequence := uint8(1); sequence <= 2; sequence++ {
+		if err := driver.Write(frame); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		first := readSACNPacket(t, conn)
+		second := readSACNPacket(t, conn)
+
+		if first.universe != 5 || second.universe != 6 {
+			t.Errorf("Universes = %d, %d, want 5, 6", first.universe, second.universe)
+		}
+		if first.priority != 150 {
+			t.Errorf("Priority = %d, want 150", first.priority)
+		}
+		if first.sequence != sequence || second.sequence != sequence {
+			t.Errorf("Sequences = %d, %d, want %d", first.sequence, second.sequence, sequence)
+		}
+		if !bytes.Equal(first.data, frame[:510]) || !bytes.Equal(second.data, frame[510:]) {
+			t.Error("Universe data does not match the frame")
+		}
+	}
+}
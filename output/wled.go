@@ -0,0 +1,93 @@
+package output
+
+import (
+	"ddp-sender/config"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const (
+	WLED_REALTIME_PORT  = 21324
+	WLED_DNRGB_PIXELS   = 489 // Pixels per DNRGB packet.
+	wledProtocolWARLS   = 1
+	wledProtocolDRGB    = 2
+	wledProtocolDNRGB   = 4
+	wledMaxPacketLength = 2 + 3*490 // DRGB with its maximum of 490 LEDs.
+)
+
+// WLEDDriver sends pixel data using the WLED realtime UDP protocols (WARLS, DRGB or DNRGB).
+type WLEDDriver struct {
+	conn    *net.UDPConn
+	mode    string
+	timeout byte
+	offset  int
+	packet  []byte
+}
+
+// NewWLEDDriver sends to address, on WLED_REALTIME_PORT when it has no port.
+func NewWLEDDriver(address string, offset int, opts config.WLEDConfig) (*WLEDDriver, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(WLED_REALTIME_PORT))
+	}
+	destination, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, destination)
+	if err != nil {
+		return nil, err
+	}
+	return &WLEDDriver{
+		conn:    conn,
+		mode:    opts.Mode,
+		timeout: byte(opts.Timeout),
+		offset:  offset,
+		packet:  make([]byte, 0, wledMaxPacketLength),
+	}, nil
+}
+
+func (d *WLEDDriver) Write(data []byte) error {
+	switch d.mode {
+	case config.WLED_MODE_WARLS:
+		return d.writeWARLS(data)
+	case config.WLED_MODE_DRGB:
+		return d.send(append(d.packet[:0], wledProtocolDRGB, d.timeout), data)
+	case config.WLED_MODE_DNRGB:
+		return d.writeDNRGB(data)
+	default:
+		return fmt.Errorf("unsupported WLED mode %q", d.mode)
+	}
+}
+
+func (d *WLEDDriver) writeWARLS(data []byte) error {
+	d.packet = append(d.packet[:0], wledProtocolWARLS, d.timeout)
+	for i := 0; 3*i+2 < len(data); i++ {
+		d.packet = append(d.packet, byte(d.offset+i), data[3*i], data[3*i+1], data[3*i+2])
+	}
+	_, err := d.conn.Write(d.packet)
+	return err
+}
+
+// writeDNRGB sends data in chunks of 489 LEDs, each starting at its own LED index.
+func (d *WLEDDriver) writeDNRGB(data []byte) error {
+	for start := 0; start < len(data); start += 3 * WLED_DNRGB_PIXELS {
+		end := min(start+3*WLED_DNRGB_PIXELS, len(data))
+		index := d.offset + start/3
+		header := append(d.packet[:0], wledProtocolDNRGB, d.timeout, byte(index>>8), byte(index))
+		if err := d.send(header, data[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *WLEDDriver) send(header []byte, data []byte) error {
+	d.packet = append(header, data...)
+	_, err := d.conn.Write(d.packet)
+	return err
+}
+
+func (d *WLEDDriver) Close() error {
+	return d.conn.Close()
+}
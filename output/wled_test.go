@@ -0,0 +1,108 @@
+package output_test
+
+import (
+	"bytes"
+	"ddp-sender/config"
+	"ddp-sender/output"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWLEDDriver_DefaultPort(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: output.WLED_REALTIME_PORT})
+	if err != nil {
+		t.Skipf("WLED realtime port unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	driver, err := output.NewWLEDDriver("127.0.0.1", 0, config.WLEDConfig{Mode: config.WLED_MODE_DRGB, Timeout: 5})
+	if err != nil {
+		t.Fatalf("NewWLEDDriver() error = %v", err)
+	}
+	defer driver.Close()
+	if err := driver.Write([]byte{1, 2, 3}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	buf := make([]byte, 16)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("Failed to read packet: %v", err)
+	}
+	if want := []byte{2, 5, 1, 2, 3}; !bytes.Equal(buf[:n], want) {
+		t.Errorf("Packet = %v, want %v", buf[:n], want)
+	}
+}
+
+func TestWLEDDriver_Write(t *testing.T) {
+	frame := make([]byte, 500*3)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		offset  int
+		frame   []byte
+		packets [][]byte
+	}{
+		{
+			name:    "DRGB",
+			mode:    config.WLED_MODE_DRGB,
+			frame:   frame[:6],
+			packets: [][]byte{{2, 5, 0, 1, 2, 3, 4, 5}},
+		},
+		{
+			name:    "WARLS",
+			mode:    config.WLED_MODE_WARLS,
+			offset:  7,
+			frame:   frame[:6],
+			packets: [][]byte{{1, 5, 7, 0, 1, 2, 8, 3, 4, 5}},
+		},
+		{
+			name:   "DNRGB chunks",
+			mode:   config.WLED_MODE_DNRGB,
+			offset: 10,
+			frame:  frame,
+			packets: [][]byte{
+				append([]byte{4, 5, 0, 10}, frame[:489*3]...),
+				append([]byte{4, 5, 1, 243}, frame[489*3:]...), // Start index 10 + 489 = 499.
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatalf("Failed to listen on UDP: %v", err)
+			}
+			defer conn.Close()
+
+			driver, err := output.NewWLEDDriver(conn.LocalAddr().String(), tt.offset, config.WLEDConfig{Mode: tt.mode, Timeout: 5})
+			if err != nil {
+				t.Fatalf("NewWLEDDriver() error = %v", err)
+			}
+			defer driver.Close()
+
+			if err := driver.Write(tt.frame); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			buf := make([]byte, 2048)
+			for i, want := range tt.packets {
+				conn.SetReadDeadline(time.Now().Add(time.Second))
+				n, _, err := conn.ReadFromUDP(buf)
+				if err != nil {
+					t.Fatalf("Failed to read packet %d: %v", i, err)
+				}
+				if !bytes.Equal(buf[:n], want) {
+					t.Errorf("Packet %d = %v, want %v", i, buf[:min(n, 12)], want[:min(len(want), 12)])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package scheduler drives the frame clock: every tick advances the effects,
+// composes the frame and hands it to the outputs.
+package scheduler
+
+import (
+	"context"
+	"ddp-sender/led"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// FrameSink receives every composed frame. The frame is reused by the next tick.
+type FrameSink interface {
+	Send(frame []colorful.Color)
+}
+
+// Stats is a snapshot of the frame clock counters.
+type Stats struct {
+	Frames     int64         `json:"frames"`
+	Late       int64         `json:"late"`    // Frames that started after their deadline.
+	Skipped    int64         `json:"skipped"` // Ticks dropped to catch up after an overrun.
+	LastRender time.Duration `json:"lastRender"`
+	Interval   time.Duration `json:"interval"`
+}
+
+type Scheduler struct {
+	array    led.LEDArray
+	sink     FrameSink
+	interval time.Duration
+	frame    []colorful.Color // Reused by every tick.
+
+	frames     atomic.Int64
+	late       atomic.Int64
+	skipped    atomic.Int64
+	lastRender atomic.Int64
+}
+
+func NewScheduler(array led.LEDArray, sink FrameSink, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		array:    array,
+		sink:     sink,
+		interval: interval,
+	}
+}
+
+// Run renders frames on an absolute schedule (start + n*interval) so timing errors
+// do not accumulate, until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	next := time.Now()
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+
+		now := time.Now()
+		if behind := now.Sub(next); behind > s.interval/2 {
+			s.late.Add(1)
+			// Do not burst missed frames, skip the ticks that already passed.
+			if missed := int64(behind / s.interval); missed > 0 {
+				s.skipped.Add(missed)
+				next = next.Add(time.Duration(missed) * s.interval)
+			}
+		}
+
+		s.Tick()
+		s.lastRender.Store(int64(time.Since(now)))
+
+		next = next.Add(s.interval)
+		timer.Reset(time.Until(next))
+	}
+}
+
+// Tick advances the effects and sends the resulting frame.
+func (s *Scheduler) Tick() {
+	s.array.SetNextEffectValues()
+	s.frame = s.array.GetFrame(s.frame)
+	s.sink.Send(s.frame)
+	s.frames.Add(1)
+}
+
+func (s *Scheduler) Stats() Stats {
+	return Stats{
+		Frames:     s.frames.Load(),
+		Late:       s.late.Load(),
+		Skipped:    s.skipped.Load(),
+		LastRender: time.Duration(s.lastRender.Load()),
+		Interval:   s.interval,
+	}
+}
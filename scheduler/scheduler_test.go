@@ -0,0 +1,92 @@
+package scheduler_test
+
+import (
+	"context"
+	"ddp-sender/scheduler"
+	"ddp-sender/updater/effects"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+type fakeArray struct {
+	sync.Mutex
+	ticks int
+	slow  time.Duration // Render time of the third tick.
+}
+
+func (a *fakeArray) GetArray() []byte {
+	a.Lock()
+	defer a.Unlock()
+	return []byte{byte(a.ticks), 0, 0}
+}
+
+func (a *fakeArray) GetFrame(dst []colorful.Color) []colorful.Color {
+	a.Lock()
+	defer a.Unlock()
+	return append(dst[:0], colorful.Color{R: float64(a.ticks)})
+}
+
+func (a *fakeArray) SetNextEffectValues() {
+	a.Lock()
+	a.ticks++
+	ticks := a.ticks
+	a.Unlock()
+	if ticks == 3 {
+		time.Sleep(a.slow)
+	}
+}
+
+func (a *fakeArray) SetLED(ledNumber int, on bool, red, green, blue uint8)                     {}
+func (a *fakeArray) SetLEDs(first, last int, on bool, red, green, blue uint8)                  {}
+func (a *fakeArray) SetLEDsEffect(effect effects.Effect)                                       {}
+func (a *fakeArray) FadeEffect(effects.Effect, float64, float64, time.Duration, time.Duration) {}
+
+type fakeSink struct {
+	sync.Mutex
+	frames [][]colorful.Color
+}
+
+func (s *fakeSink) Send(frame []colorful.Color) {
+	s.Lock()
+	defer s.Unlock()
+	s.frames = append(s.frames, slices.Clone(frame))
+}
+
+func TestScheduler_TickSendsRenderedFrame(t *testing.T) {
+	array := &fakeArray{}
+	sink := &fakeSink{}
+	s := scheduler.NewScheduler(array, sink, time.Millisecond)
+
+	s.Tick()
+	s.Tick()
+
+	if len(sink.frames) != 2 || sink.frames[0][0].R != 1 || sink.frames[1][0].R != 2 {
+		t.Errorf("Frames = %v, want one frame rendered after each effect step", sink.frames)
+	}
+	if s.Stats().Frames != 2 {
+		t.Errorf("Stats().Frames = %d, want 2", s.Stats().Frames)
+	}
+}
+
+func TestScheduler_SkipsTicksAfterOverrun(t *testing.T) {
+	array := &fakeArray{slow: 50 * time.Millisecond}
+	sink := &fakeSink{}
+	s := scheduler.NewScheduler(array, sink, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	stats := s.Stats()
+	if stats.Late == 0 || stats.Skipped < 3 {
+		t.Errorf("Stats() = %+v, want the overrun to be reported as late and skipped frames", stats)
+	}
+	// 120ms at 10ms per frame minus the skipped ticks, without a burst of catch-up frames.
+	if stats.Frames+stats.Skipped > 13 {
+		t.Errorf("Stats() = %+v, more frames than ticks", stats)
+	}
+}
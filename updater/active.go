@@ -0,0 +1,161 @@
+package updater
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/led"
+	"ddp-sender/listener"
+	"ddp-sender/updater/mappings/custom"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// ActiveMapping is a mapping file active next to the switchable mapping, with its own effects.
+type ActiveMapping struct {
+	File     string `json:"file"`
+	Channels []int  `json:"channels"`        // Empty when it follows the routes of the switchable mapping.
+	Layer    *int   `json:"layer,omitempty"` // Layer of all its effects instead of the preset layers.
+	Routed   bool   `json:"routed"`          // Bound by the routes of the configuration, it cannot be deactivated.
+}
+
+type activeMapping struct {
+	ActiveMapping
+	mapper *custom.CustomMapper
+}
+
+// MapMessage maps a message to the mapping, program changes only switch the switchable mapping.
+func (a *activeMapping) MapMessage(array led.LEDArray, message listener.MidiMessage) {
+	if message.Kind != listener.KIND_PROGRAM_CHANGE {
+		a.mapper.MapMessage(array, message)
+	}
+}
+
+// mapSwitchable maps a message routed to the switchable mapping to it and to the active
+// mappings following its routes.
+func (u *Updater) mapSwitchable(array led.LEDArray, message listener.MidiMessage) {
+	u.customMapper.MapMessage(array, message)
+
+	u.activeMutex.RLock()
+	defer u.activeMutex.RUnlock()
+	for _, active := range u.active {
+		if len(active.Channels) == 0 {
+			active.MapMessage(array, message)
+		}
+	}
+}
+
+// mapActive maps a message to the active mappings bound to its channel, or to all of them for real-time messages.
+func (u *Updater) mapActive(message listener.MidiMessage) {
+	u.activeMutex.RLock()
+	defer u.activeMutex.RUnlock()
+	for _, active := range u.active {
+		if message.Kind.IsRealtime() || slices.Contains(active.Channels, int(message.Channel)) {
+			active.MapMessage(u.array, message)
+		}
+	}
+}
+
+// ActivateMapping loads a mapping file next to the switchable mapping. Activating an active
+// file again replaces it, ending its effects.
+func (u *Updater) ActivateMapping(mapping ActiveMapping) error {
+	for _, channel := range mapping.Channels {
+		if channel < 1 || channel > 16 {
+			return fmt.Errorf("channels must be between 1 and 16 (got %d)", channel)
+		}
+	}
+	for _, routed := range u.routedMappings() {
+		if routed.File == mapping.File {
+			return fmt.Errorf("mapping %q is bound by the routes of the configuration", mapping.File)
+		}
+	}
+	mapper, err := u.customMapper.NewMapper(mapping.File, mapping.Layer)
+	if err != nil {
+		return err
+	}
+	mapping.Routed = false
+	active := &activeMapping{ActiveMapping: mapping, mapper: mapper}
+
+	u.activeMutex.Lock()
+	defer u.activeMutex.Unlock()
+	log.Printf("Activated mapping %s on channels %v\n", mapping.File, mapping.Channels)
+	for i, other := range u.active {
+		if other.File == mapping.File {
+			other.mapper.ClearAllEffects()
+			u.active[i] = active
+			return nil
+		}
+	}
+	u.active = append(u.active, active)
+	return nil
+}
+
+// DeactivateMapping ends the effects of an active mapping and removes it.
+func (u *Updater) DeactivateMapping(file string) error {
+	u.activeMutex.Lock()
+	defer u.activeMutex.Unlock()
+	for i, active := range u.active {
+		if active.File == file {
+			active.mapper.ClearAllEffects()
+			u.active = slices.Delete(u.active, i, i+1)
+			log.Printf("Deactivated mapping %s\n", file)
+			return nil
+		}
+	}
+	return fmt.Errorf("mapping %q is not active", file)
+}
+
+// ActiveMappings returns the mappings bound by the routes, then the activated ones, the
+// switchable mapping excluded.
+func (u *Updater) ActiveMappings() []ActiveMapping {
+	mappings := u.routedMappings()
+
+	u.activeMutex.RLock()
+	defer u.activeMutex.RUnlock()
+	for _, active := range u.active {
+		mapping := active.ActiveMapping
+		mapping.Channels = append([]int{}, mapping.Channels...)
+		mappings = append(mappings, mapping)
+	}
+	return mappings
+}
+
+// ReloadMapping reloads the routed and active mappers of file after it is saved.
+func (u *Updater) ReloadMapping(file string) error {
+	mappers := slices.Clone(u.mappers[1:])
+	u.activeMutex.RLock()
+	for _, active := range u.active {
+		mappers = append(mappers, active.mapper)
+	}
+	u.activeMutex.RUnlock()
+
+	for _, mapper := range mappers {
+		if mapper.CurrentMapping() != file {
+			continue
+		}
+		if err := mapper.LoadMappingFromFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedMappings returns the mapping files of the routes with the channels they are routed from.
+func (u *Updater) routedMappings() []ActiveMapping {
+	mappings := []ActiveMapping{}
+	for _, r := range u.routes {
+		for _, handler := range r.Handlers {
+			if handler.Type != config.HANDLER_MAPPING || handler.File == "" {
+				continue
+			}
+			i := slices.IndexFunc(mappings, func(m ActiveMapping) bool { return m.File == handler.File })
+			if i < 0 {
+				mappings = append(mappings, ActiveMapping{File: handler.File, Routed: true})
+				i = len(mappings) - 1
+			}
+			if !slices.Contains(mappings[i].Channels, r.Channel) {
+				mappings[i].Channels = append(mappings[i].Channels, r.Channel)
+			}
+		}
+	}
+	return mappings
+}
@@ -13,6 +13,7 @@ type Decay struct {
 	Color colorful.Color
 	DecayOptions
 	util.DoneState
+	LayerOptions
 }
 
 type DecayOptions struct {
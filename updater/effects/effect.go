@@ -13,6 +13,13 @@ type Effect interface {
 	SetDone() bool
 	OffEvent(velocity uint8)
 	Retrigger(velocity uint8) bool // Retrigger receives a new trigger for the effect and returns if the effect is done or not.
+	GetLayer() LayerOptions
+	SetLayer(options LayerOptions)
+}
+
+// Pressure is implemented by effects that follow the aftertouch of the note that triggered them.
+type Pressure interface {
+	SetPressure(pressure uint8)
 }
 
 type EffectOptions interface{}
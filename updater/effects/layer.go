@@ -0,0 +1,86 @@
+package effects
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+type BlendMode string
+
+const (
+	BLEND_REPLACE  BlendMode = "replace"  // Non-black pixels overwrite the layers below.
+	BLEND_ADD      BlendMode = "add"      // Channels are added (clamped).
+	BLEND_MAX      BlendMode = "max"      // Brightest channel wins.
+	BLEND_MULTIPLY BlendMode = "multiply" // Layers below are multiplied by the effect, black included.
+	BLEND_ALPHA    BlendMode = "alpha"    // Non-black pixels are mixed over the layers below by opacity.
+)
+
+// LayerOptions places an effect in the composition stack. Effects are composed from
+// the lowest layer to the highest, in trigger order within the same layer.
+type LayerOptions struct {
+	Layer   int       `json:"layer,omitempty"`
+	Blend   BlendMode `json:"blend,omitempty"`   // Defaults to replace.
+	Opacity *float64  `json:"opacity,omitempty"` // 0-1, 0 is invisible, defaults to fully opaque.
+}
+
+func (l *LayerOptions) GetLayer() LayerOptions {
+	return *l
+}
+
+func (l *LayerOptions) SetLayer(options LayerOptions) {
+	*l = options
+}
+
+// Validate checks the blend mode and opacity.
+func (l LayerOptions) Validate() error {
+	switch l.Blend {
+	case "", BLEND_REPLACE, BLEND_ADD, BLEND_MAX, BLEND_MULTIPLY, BLEND_ALPHA:
+	default:
+		return fmt.Errorf("unknown blend mode %q", l.Blend)
+	}
+	if l.Opacity != nil && (*l.Opacity < 0 || *l.Opacity > 1) {
+		return fmt.Errorf("opacity must be between 0 and 1 (got %f)", *l.Opacity)
+	}
+	return nil
+}
+
+// Compose blends src over dst according to the layer blend mode and opacity.
+func (l LayerOptions) Compose(dst, src colorful.Color) colorful.Color {
+	opacity := 1.0
+	if l.Opacity != nil {
+		opacity = *l.Opacity
+	}
+	if opacity == 0 {
+		return dst
+	}
+
+	var blended colorful.Color
+	switch l.Blend {
+	case BLEND_ADD:
+		blended = colorful.Color{R: dst.R + src.R, G: dst.G + src.G, B: dst.B + src.B}.Clamped()
+	case BLEND_MAX:
+		blended = colorful.Color{R: math.Max(dst.R, src.R), G: math.Max(dst.G, src.G), B: math.Max(dst.B, src.B)}
+	case BLEND_MULTIPLY:
+		blended = colorful.Color{R: dst.R * src.R, G: dst.G * src.G, B: dst.B * src.B}
+	default: // BLEND_REPLACE, BLEND_ALPHA
+		if src.AlmostEqualRgb(colorful.Color{}) {
+			// Black is transparent.
+			return dst
+		}
+		if l.Blend != BLEND_ALPHA {
+			return src
+		}
+		blended = src
+	}
+
+	if opacity == 1 {
+		return blended
+	}
+	return colorful.Color{
+		R: dst.R + (blended.R-dst.R)*opacity,
+		G: dst.G + (blended.G-dst.G)*opacity,
+		B: dst.B + (blended.B-dst.B)*opacity,
+	}
+}
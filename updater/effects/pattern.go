@@ -0,0 +1,41 @@
+package effects
+
+import (
+	"ddp-sender/util"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Pattern shows a fixed color per LED until it is turned off, e.g. calibration test patterns.
+type Pattern struct {
+	Range  []int
+	Colors []colorful.Color
+	util.DoneState
+	LayerOptions
+}
+
+func (p *Pattern) GetRange() []int {
+	return p.Range
+}
+
+func (p *Pattern) NextValues() []colorful.Color {
+	if p.IsDone() {
+		return make([]colorful.Color, len(p.Range))
+	}
+	return p.Colors
+}
+
+func (p *Pattern) OffEvent(velocity uint8) {
+	p.SetDone()
+}
+
+func (p *Pattern) Retrigger(velocity uint8) bool {
+	return p.SetDone()
+}
+
+func NewPattern(ledRange []int, colors []colorful.Color) *Pattern {
+	return &Pattern{
+		Range:  ledRange,
+		Colors: colors,
+	}
+}
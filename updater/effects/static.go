@@ -2,14 +2,18 @@ package effects
 
 import (
 	"ddp-sender/util"
+	"sync"
 
 	"github.com/lucasb-eyer/go-colorful"
 )
 
 type Static struct {
-	Range []int
-	Color colorful.Color
+	Range     []int
+	Color     colorful.Color
+	baseColor colorful.Color // Color before the velocity adjustment, used by SetPressure.
+	colorLock sync.RWMutex
 	util.DoneState
+	LayerOptions
 }
 
 func (s *Static) GetRange() []int {
@@ -22,7 +26,9 @@ func (s *Static) NextValues() []colorful.Color {
 	if s.IsDone() {
 		return values
 	} else {
+		s.colorLock.RLock()
 		color = s.Color
+		s.colorLock.RUnlock()
 	}
 	for i := range s.Range {
 		values[i] = color
@@ -38,9 +44,17 @@ func (s *Static) Retrigger(velocity uint8) bool {
 	return s.SetDone()
 }
 
+// SetPressure adjusts the brightness to the aftertouch pressure like the velocity did.
+func (s *Static) SetPressure(pressure uint8) {
+	s.colorLock.Lock()
+	defer s.colorLock.Unlock()
+	s.Color = adjustColorToVelocity(s.baseColor, pressure)
+}
+
 func NewStatic(ledRange []int, color colorful.Color, velocity uint8) *Static {
 	return &Static{
-		Range: ledRange,
-		Color: adjustColorToVelocity(color, velocity),
+		Range:     ledRange,
+		Color:     adjustColorToVelocity(color, velocity),
+		baseColor: color,
 	}
 }
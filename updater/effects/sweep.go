@@ -13,6 +13,7 @@ type Sweep struct {
 	SweepOptions
 	currentStep float64
 	util.DoneState
+	LayerOptions
 	rangeLength int
 }
 
@@ -16,6 +16,7 @@ type SyncWalk struct {
 	currentStep int
 	stepLock    sync.RWMutex
 	util.DoneState
+	LayerOptions
 }
 
 type SyncWalkOptions struct {
@@ -63,6 +64,13 @@ func (s *SyncWalk) Retrigger(velocity uint8) bool {
 	return s.IsDone()
 }
 
+// SetPressure replaces the velocity of the walk with the aftertouch pressure.
+func (s *SyncWalk) SetPressure(pressure uint8) {
+	s.stepLock.Lock()
+	defer s.stepLock.Unlock()
+	s.Velocity = pressure
+}
+
 func NewSyncWalk(ledRange []int, color colorful.Color, velocity uint8, opts SyncWalkOptions) *SyncWalk {
 	return &SyncWalk{
 		Range:           ledRange,
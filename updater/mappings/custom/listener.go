@@ -1,13 +1,22 @@
 package custom
 
 import (
+	"context"
+	"ddp-sender/config"
+	"ddp-sender/util"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 )
 
+// SwitchMappingRequest switches to File. Transition and FadeMs override the type and
+// duration of the configured transition, a FadeMs of 0 included.
 type SwitchMappingRequest struct {
-	File string `json:"file"`
+	File       string `json:"file"`
+	Transition string `json:"transition,omitempty"` // cut, finish, crossfade or black.
+	FadeMs     *int   `json:"fadeMs,omitempty"`
 }
 
 func (c *CustomMapper) SwitchMappingHandler(w http.ResponseWriter, req *http.Request) {
@@ -20,7 +29,14 @@ func (c *CustomMapper) SwitchMappingHandler(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	err = c.SwitchMapping(request.File)
+	transition := c.Transition()
+	if request.Transition != "" {
+		transition.Type = request.Transition
+	}
+	if request.FadeMs != nil {
+		transition.Duration = config.Duration(time.Duration(*request.FadeMs) * time.Millisecond)
+	}
+	err = c.SwitchMapping(request.File, &transition)
 	if err != nil {
 		w.WriteHeader(400)
 		w.Write([]byte(err.Error()))
@@ -32,7 +48,8 @@ func (c *CustomMapper) SwitchMappingHandler(w http.ResponseWriter, req *http.Req
 	w.WriteHeader(200)
 }
 
-func (c *CustomMapper) RunListener() error {
-	http.HandleFunc("/switchMapping", c.SwitchMappingHandler)
-	return http.ListenAndServe(":8080", nil)
+func (c *CustomMapper) RunListener(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/switchMapping", c.SwitchMappingHandler)
+	return util.ServeHTTP(ctx, &http.Server{Addr: fmt.Sprintf(":%d", c.listenerPort), Handler: mux})
 }
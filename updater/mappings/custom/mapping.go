@@ -2,6 +2,7 @@ package custom
 
 import (
 	"ddp-sender/config"
+	"ddp-sender/layout"
 	"ddp-sender/led"
 	"ddp-sender/listener"
 	"ddp-sender/updater/effects"
@@ -11,59 +12,176 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/lucasb-eyer/go-colorful"
 )
 
 type CustomMapper struct {
 	sync.RWMutex
-	Mappings map[uint8]Mapping
-	Effects  map[uint8]effects.Effect
-	ledArray led.LEDArray
+	Mappings       map[uint8]Mapping
+	Effects        map[uint8]effects.Effect
+	ledArray       led.LEDArray
+	layout         *layout.Layout
+	mappingsDir    string
+	listenerPort   int
+	currentMapping string
+	programs       []string // Mapping files selected by program change.
+	layer          *int     // Layer of every effect instead of the preset layers, when set.
+	transition     config.TransitionConfig
+	fadeInStart    time.Time // Effects triggered before fadeInEnd fade in with the last transition.
+	fadeInEnd      time.Time
 }
 
 type MappingFile struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	Presets     []struct {
-		Name    string          `json:"name"`
-		Note    uint8           `json:"note"`
-		First   int             `json:"first"`
-		Last    int             `json:"last"`
-		Step    int             `json:"step"`
-		Color   string          `json:"color"`
-		Effect  string          `json:"effect"`
-		Options json.RawMessage `json:"options"`
-	} `json:"presets"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Zones       map[string]layout.Zone `json:"zones,omitempty"` // Shadow the global zones of the layout.
+	Presets     []Preset               `json:"presets"`
+}
+
+// Preset maps a note to an effect. The LEDs are a named zone of the mapping file or
+// the layout, a layout rectangle or, when neither is set, the first/last/step range of the canvas.
+type Preset struct {
+	Name    string          `json:"name"`
+	Note    uint8           `json:"note"`
+	First   int             `json:"first"`
+	Last    int             `json:"last"`
+	Step    int             `json:"step"`
+	Zone    string          `json:"zone,omitempty"`
+	Rect    *layout.Rect    `json:"rect,omitempty"`
+	Color   string          `json:"color"`
+	Effect  string          `json:"effect"`
+	Options json.RawMessage `json:"options"`
+	effects.LayerOptions
+}
+
+// Range returns the LEDs targeted by the preset, zones are looked up in zones then in the layout.
+func (p *Preset) Range(l *layout.Layout, zones map[string]layout.Zone) ([]int, error) {
+	switch {
+	case p.Zone != "" && p.Rect != nil:
+		return nil, fmt.Errorf("zone and rect are mutually exclusive")
+	case p.Zone != "":
+		return l.Zone(p.Zone, zones)
+	case p.Rect != nil:
+		return l.Rect(*p.Rect), nil
+	default:
+		return util.MakeRange(p.First, p.Last, p.Step), nil
+	}
+}
+
+// Validate checks the fields of every zone and preset that would prevent it from loading.
+// Zone references are checked by CustomMapper.Resolve, they may point to global zones.
+func (m *MappingFile) Validate() error {
+	for name, zone := range m.Zones {
+		if err := zone.Validate(); err != nil {
+			return fmt.Errorf("zone %q: %v", name, err)
+		}
+	}
+	for _, preset := range m.Presets {
+		if _, err := colorful.Hex(preset.Color); err != nil {
+			return fmt.Errorf("preset %q: invalid color %q", preset.Name, preset.Color)
+		}
+		if err := preset.LayerOptions.Validate(); err != nil {
+			return fmt.Errorf("preset %q: %v", preset.Name, err)
+		}
+		if preset.Zone != "" && preset.Rect != nil {
+			return fmt.Errorf("preset %q: zone and rect are mutually exclusive", preset.Name)
+		}
+		if preset.Rect != nil && (preset.Rect.Width <= 0 || preset.Rect.Height <= 0) {
+			return fmt.Errorf("preset %q: rect width and height must be positive", preset.Name)
+		}
+	}
+	return nil
 }
 
 type Mapping struct {
+	Name    string // Preset name.
 	Range   []int
 	Color   colorful.Color
 	Effect  string
 	Options json.RawMessage
+	Layer   effects.LayerOptions
 }
 
+// Standard MIDI channel mode controllers followed by the custom mapper.
+const (
+	CC_ALL_SOUND_OFF = 120 // Stops every effect at once.
+	CC_ALL_NOTES_OFF = 123 // Sends a note off to every effect.
+)
+
+// MapMessage maps a message of the custom channel, or a real-time message, to the running effects:
+//   - notes trigger and release presets
+//   - poly aftertouch follows the effect of its note and channel aftertouch every effect (effects.Pressure)
+//   - program change n switches to the n-th mapping of config.Config.Programs
+//   - control changes CC_ALL_SOUND_OFF and CC_ALL_NOTES_OFF, and transport stop, end the effects
+//
+// Pitch bend, clock, start and continue are not mapped yet.
 func (c *CustomMapper) MapMessage(array led.LEDArray, message listener.MidiMessage) {
-	c.RLock()
-	defer c.RUnlock()
-	if _, ok := c.Mappings[message.Note]; ok {
+	switch message.Kind {
+	case listener.KIND_PROGRAM_CHANGE:
+		if int(message.Value) >= len(c.programs) {
+			log.Printf("No mapping for program %d\n", message.Value)
+			return
+		}
+		if err := c.SwitchMapping(c.programs[message.Value], nil); err != nil {
+			log.Println("Program change error:", err)
+		}
+		return
+	case listener.KIND_STOP:
+		c.ClearAllEffects()
+		return
+	case listener.KIND_CONTROL_CHANGE:
+		switch message.Controller {
+		case CC_ALL_SOUND_OFF:
+			c.ClearAllEffects()
+		case CC_ALL_NOTES_OFF:
+			c.releaseAllEffects()
+		}
+		return
+	}
+
+	// Note ons write the running effects.
+	c.Lock()
+	defer c.Unlock()
+	switch message.Kind {
+	case listener.KIND_NOTE:
+		if _, ok := c.Mappings[message.Note]; !ok {
+			return
+		}
 		if message.On {
 			c.triggerEffectForNote(array, message.Note, message.Velocity)
-		} else {
-			if effect, ok := c.Effects[message.Note]; ok {
-				effect.OffEvent(message.Velocity)
+		} else if effect, ok := c.Effects[message.Note]; ok {
+			effect.OffEvent(message.Velocity)
+		}
+	case listener.KIND_POLY_AFTERTOUCH:
+		if effect, ok := c.Effects[message.Note].(effects.Pressure); ok {
+			effect.SetPressure(message.Value)
+		}
+	case listener.KIND_CHANNEL_AFTERTOUCH:
+		for _, effect := range c.Effects {
+			if effect, ok := effect.(effects.Pressure); ok {
+				effect.SetPressure(message.Value)
 			}
 		}
+	}
+}
 
+// releaseAllEffects sends a note off to every running effect.
+func (c *CustomMapper) releaseAllEffects() {
+	c.RLock()
+	defer c.RUnlock()
+	for _, effect := range c.Effects {
+		effect.OffEvent(0)
 	}
 }
 
 // TriggerPreset manually triggers a preset effect by MIDI note
 func (c *CustomMapper) TriggerPreset(note uint8, velocity uint8) error {
-	c.RLock()
-	defer c.RUnlock()
+	c.Lock()
+	defer c.Unlock()
 
 	if _, ok := c.Mappings[note]; !ok {
 		return fmt.Errorf("no mapping found for note %d", note)
@@ -73,10 +191,23 @@ func (c *CustomMapper) TriggerPreset(note uint8, velocity uint8) error {
 	return nil
 }
 
-// TriggerPresetOff manually turns off a preset effect by MIDI note
-func (c *CustomMapper) TriggerPresetOff(note uint8, velocity uint8) error {
+// PresetNote returns the note of the preset named name in the active mapping. Spaces in
+// preset names may be written as underscores, as OSC addresses cannot contain them.
+func (c *CustomMapper) PresetNote(name string) (uint8, error) {
 	c.RLock()
 	defer c.RUnlock()
+	for note, mapping := range c.Mappings {
+		if mapping.Name == name || strings.ReplaceAll(mapping.Name, " ", "_") == name {
+			return note, nil
+		}
+	}
+	return 0, fmt.Errorf("no preset named %q", name)
+}
+
+// TriggerPresetOff manually turns off a preset effect by MIDI note
+func (c *CustomMapper) TriggerPresetOff(note uint8, velocity uint8) error {
+	c.Lock()
+	defer c.Unlock()
 
 	if _, ok := c.Mappings[note]; !ok {
 		return fmt.Errorf("no mapping found for note %d", note)
@@ -106,7 +237,7 @@ func (c *CustomMapper) ClearAllEffects() error {
 }
 
 // TriggerPreviewEffect triggers a temporary effect for preview without needing a saved mapping
-func (c *CustomMapper) TriggerPreviewEffect(first, last, step int, colorHex, effectType string, optionsJson json.RawMessage) error {
+func (c *CustomMapper) TriggerPreviewEffect(first, last, step int, colorHex, effectType string, optionsJson json.RawMessage, layer effects.LayerOptions) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -118,6 +249,9 @@ func (c *CustomMapper) TriggerPreviewEffect(first, last, step int, colorHex, eff
 	if err != nil {
 		return fmt.Errorf("invalid color format: %v", err)
 	}
+	if err := layer.Validate(); err != nil {
+		return err
+	}
 
 	// Create temporary mapping
 	tempMapping := Mapping{
@@ -125,6 +259,7 @@ func (c *CustomMapper) TriggerPreviewEffect(first, last, step int, colorHex, eff
 		Color:   color,
 		Effect:  effectType,
 		Options: optionsJson,
+		Layer:   layer,
 	}
 
 	// Use special preview note (255 = preview)
@@ -140,7 +275,7 @@ func (c *CustomMapper) TriggerPreviewEffect(first, last, step int, colorHex, eff
 	}
 
 	// Create and trigger the effect with max velocity
-	effect, err := tempMapping.getNewEffect(127)
+	effect, err := tempMapping.NewEffect(127)
 	if err != nil {
 		return fmt.Errorf("failed to create effect: %v", err)
 	}
@@ -190,7 +325,7 @@ func (c *CustomMapper) ClearPreviewEffects() error {
 	return nil
 }
 
-// triggerEffectForNote is a helper method to trigger an effect for a specific note
+// triggerEffectForNote is a helper method to trigger an effect for a specific note, c must be locked.
 func (c *CustomMapper) triggerEffectForNote(array led.LEDArray, note uint8, velocity uint8) {
 	mapping := c.Mappings[note]
 
@@ -201,18 +336,91 @@ func (c *CustomMapper) triggerEffectForNote(array led.LEDArray, note uint8, velo
 			return
 		}
 	}
-	effect, err := mapping.getNewEffect(velocity)
+	effect, err := mapping.NewEffect(velocity)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 	if array != nil {
+		c.fadeIn(array, effect)
 		array.SetLEDsEffect(effect)
 	}
 	c.Effects[note] = effect
 }
 
-func (m *Mapping) getNewEffect(velocity uint8) (effects.Effect, error) {
+// fadeIn fades an effect triggered before the end of the last transition in with it.
+func (c *CustomMapper) fadeIn(array led.LEDArray, effect effects.Effect) {
+	now := time.Now()
+	if !now.Before(c.fadeInEnd) {
+		return
+	}
+	if now.Before(c.fadeInStart) {
+		array.FadeEffect(effect, 0, 1, c.fadeInStart.Sub(now), c.fadeInEnd.Sub(c.fadeInStart))
+		return
+	}
+	level := float64(now.Sub(c.fadeInStart)) / float64(c.fadeInEnd.Sub(c.fadeInStart))
+	array.FadeEffect(effect, level, 1, 0, c.fadeInEnd.Sub(now))
+}
+
+// endEffects hands the running effects over to a new mapping with transition, c must be locked.
+func (c *CustomMapper) endEffects(transition config.TransitionConfig) {
+	now := time.Now()
+	duration := transition.Duration.Duration()
+	fadeOut := duration
+	c.fadeInStart, c.fadeInEnd = now, now
+	switch transition.Type {
+	case config.TRANSITION_CROSSFADE:
+		c.fadeInEnd = now.Add(duration)
+	case config.TRANSITION_BLACK:
+		fadeOut = duration / 2
+		c.fadeInStart, c.fadeInEnd = now.Add(fadeOut), now.Add(duration)
+	}
+
+	fading := (transition.Type == config.TRANSITION_CROSSFADE || transition.Type == config.TRANSITION_BLACK) && fadeOut > 0
+	for note, effect := range c.Effects {
+		switch {
+		case transition.Type == config.TRANSITION_FINISH:
+			// A walk only ends when stepped past its range, the next mapping will not step it.
+			if _, walking := effect.(*effects.SyncWalk); walking {
+				effect.SetDone()
+			} else {
+				effect.OffEvent(0)
+			}
+		case fading && c.ledArray != nil:
+			c.ledArray.FadeEffect(effect, 1, 0, 0, fadeOut)
+		default:
+			effect.SetDone()
+		}
+		delete(c.Effects, note)
+	}
+}
+
+// NewEffect creates the effect of the mapping for a note played at velocity.
+func (m *Mapping) NewEffect(velocity uint8) (effects.Effect, error) {
+	effect, err := m.newEffect(velocity)
+	if err != nil {
+		return nil, err
+	}
+	effect.SetLayer(m.Layer)
+	return effect, nil
+}
+
+// CheckEffect checks that effect is known and that its options parse, static has no options.
+func CheckEffect(effect string, options json.RawMessage) error {
+	switch effect {
+	case "static":
+		return nil
+	case "decay", "sweep", "syncWalk":
+		mapping := Mapping{Effect: effect, Options: options}
+		if _, err := mapping.newEffect(127); err != nil {
+			return fmt.Errorf("invalid %s options: %v", effect, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown effect %q", effect)
+}
+
+func (m *Mapping) newEffect(velocity uint8) (effects.Effect, error) {
 	switch m.Effect {
 	case "static":
 		return effects.NewStatic(m.Range, m.Color, velocity), nil
@@ -242,8 +450,13 @@ func (m *Mapping) getNewEffect(velocity uint8) (effects.Effect, error) {
 	}
 }
 
+// LoadMappingFromFile loads filename, ending the running effects at once.
 func (c *CustomMapper) LoadMappingFromFile(filename string) error {
-	filepath := filepath.Join(config.MAPPINGS_DIR, filename)
+	return c.loadMapping(filename, config.TransitionConfig{Type: config.TRANSITION_CUT})
+}
+
+func (c *CustomMapper) loadMapping(filename string, transition config.TransitionConfig) error {
+	filepath := filepath.Join(c.mappingsDir, filename)
 	data, err := os.ReadFile(filepath)
 	if err != nil {
 		return err
@@ -254,59 +467,146 @@ func (c *CustomMapper) LoadMappingFromFile(filename string) error {
 	if err != nil {
 		return err
 	}
+	err = mappingFile.Validate()
+	if err != nil {
+		return err
+	}
+
+	mappings, err := c.Resolve(&mappingFile)
+	if err != nil {
+		return err
+	}
 
 	c.Lock()
 	defer c.Unlock()
 
-	// Finish all effects from previous mapping
-	for key, effect := range c.Effects {
-		effect.SetDone()
-		delete(c.Effects, key)
+	// Hand the effects of the previous mapping over.
+	c.endEffects(transition)
+	c.Mappings = mappings
+
+	log.Printf("Loaded mapping '%s' with %d presets from %s\n", mappingFile.Name, len(c.Mappings), filename)
+	return nil
+}
+
+// Resolve parses the presets of a mapping file, resolving their zones and rectangles with the layout.
+func (c *CustomMapper) Resolve(mappingFile *MappingFile) (map[uint8]Mapping, error) {
+	// Resolve every zone so unused broken zones are reported too.
+	for name := range mappingFile.Zones {
+		if _, err := c.layout.Zone(name, mappingFile.Zones); err != nil {
+			return nil, fmt.Errorf("zone %q: %v", name, err)
+		}
 	}
 
-	// Parse new mapping presets
-	c.Mappings = make(map[uint8]Mapping)
+	mappings := make(map[uint8]Mapping)
 	for _, preset := range mappingFile.Presets {
-		ledRange := util.MakeRange(preset.First, preset.Last, preset.Step)
+		ledRange, err := preset.Range(c.layout, mappingFile.Zones)
+		if err != nil {
+			return nil, fmt.Errorf("preset %q: %v", preset.Name, err)
+		}
 		color, err := colorful.Hex(preset.Color)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		c.Mappings[preset.Note] = Mapping{
+		layerOptions := preset.LayerOptions
+		if c.layer != nil {
+			layerOptions.Layer = *c.layer
+		}
+		mappings[preset.Note] = Mapping{
+			Name:    preset.Name,
 			Range:   ledRange,
 			Color:   color,
 			Effect:  preset.Effect,
 			Options: preset.Options,
+			Layer:   layerOptions,
 		}
 	}
-
-	log.Printf("Loaded mapping '%s' with %d presets from %s\n", mappingFile.Name, len(c.Mappings), filename)
-	return nil
+	return mappings, nil
 }
 
-func (c *CustomMapper) SwitchMapping(filename string) error {
-	err := c.LoadMappingFromFile(filename)
+// SwitchMapping loads filename, handing the running effects over with transition, or with
+// the configured transition when nil.
+func (c *CustomMapper) SwitchMapping(filename string, transition *config.TransitionConfig) error {
+	if transition == nil {
+		transition = &c.transition
+	}
+	if err := transition.Validate(); err != nil {
+		return err
+	}
+	err := c.loadMapping(filename, *transition)
 	if err != nil {
 		return err
 	}
-	config.CURRENT_MAPPING = filename
+	c.Lock()
+	c.currentMapping = filename
+	c.Unlock()
 	return nil
 }
 
-func NewCustomMapper() *CustomMapper {
+// CurrentMapping returns the file name of the active mapping.
+func (c *CustomMapper) CurrentMapping() string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.currentMapping
+}
+
+// Transition returns the configured transition of SwitchMapping.
+func (c *CustomMapper) Transition() config.TransitionConfig {
+	return c.transition
+}
+
+// Layout returns the LED layout preset zones and rectangles are resolved with.
+func (c *CustomMapper) Layout() *layout.Layout {
+	return c.layout
+}
+
+// MappingsDir returns the directory mapping files are loaded from.
+func (c *CustomMapper) MappingsDir() string {
+	return c.mappingsDir
+}
+
+// NewCustomMapper returns a mapper loading filename from the mappings directory.
+func NewCustomMapper(cfg *config.Config, pixelLayout *layout.Layout, filename string) *CustomMapper {
 	mapper := &CustomMapper{
-		Effects: make(map[uint8]effects.Effect),
+		Effects:        make(map[uint8]effects.Effect),
+		layout:         pixelLayout,
+		mappingsDir:    cfg.MappingsDir,
+		listenerPort:   cfg.ReaperPort,
+		currentMapping: filename,
+		programs:       cfg.Programs,
+		transition:     cfg.Transition,
 	}
 
 	// Load default mapping on startup
-	err := mapper.LoadMappingFromFile(config.CURRENT_MAPPING)
+	err := mapper.LoadMappingFromFile(filename)
 	if err != nil {
-		log.Printf("Warning: Could not load default mapping '%s': %v\n", config.CURRENT_MAPPING, err)
+		log.Printf("Warning: Could not load default mapping '%s': %v\n", filename, err)
 	}
 
 	return mapper
 }
 
+// NewMapper returns a mapper of filename with its own effects, sharing the directory, layout
+// and LED array of c. When layer is set every effect of the mapping is placed on it. It follows
+// no program changes and runs no REAPER listener. Like c, it writes its effects under its write
+// lock only, so it can be mapped while the web UI reloads or clears it.
+func (c *CustomMapper) NewMapper(filename string, layer *int) (*CustomMapper, error) {
+	c.RLock()
+	mapper := &CustomMapper{
+		Effects:        make(map[uint8]effects.Effect),
+		ledArray:       c.ledArray,
+		layout:         c.layout,
+		mappingsDir:    c.mappingsDir,
+		currentMapping: filename,
+		layer:          layer,
+	}
+	c.RUnlock()
+
+	if err := mapper.LoadMappingFromFile(filename); err != nil {
+		return nil, err
+	}
+	return mapper, nil
+}
+
 // SetLEDArray sets the LED array reference for manual triggering
 func (c *CustomMapper) SetLEDArray(array led.LEDArray) {
 	c.Lock()
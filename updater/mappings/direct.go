@@ -0,0 +1,14 @@
+package mappings
+
+import (
+	"ddp-sender/led"
+	"ddp-sender/listener"
+)
+
+// DirectMapping holds the LED of the note number in red, velocity 127 at full red, until note off.
+func DirectMapping(array led.LEDArray, message listener.MidiMessage) {
+	if message.Kind == listener.KIND_NOTE {
+		red := uint8(min(255, int(message.Velocity)*255/127))
+		array.SetLED(int(message.Note), message.On, red, 0, 0)
+	}
+}
@@ -0,0 +1,266 @@
+package drums
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/layout"
+	"ddp-sender/led"
+	"ddp-sender/listener"
+	"ddp-sender/updater/effects"
+	"ddp-sender/updater/mappings/custom"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Drum groups hold their LEDs until note off or trigger an effect on note on.
+const (
+	MODE_HOLD    = "hold"
+	MODE_TRIGGER = "trigger"
+)
+
+// KitFile is a drum-kit profile: the notes of every drum light a group of LEDs
+// when hit above a velocity threshold.
+type KitFile struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Groups      []Group `json:"groups"`
+	// Default handles the notes of no group on the LED of the note number, its LEDs are ignored.
+	Default *Group `json:"default,omitempty"`
+}
+
+// Group maps the notes of a drum to LEDs. Note offs always release held LEDs,
+// the threshold only applies to note ons.
+type Group struct {
+	Name                 string          `json:"name"`
+	Notes                []uint8         `json:"notes"`
+	Threshold            uint8           `json:"threshold,omitempty"` // Note on velocities must exceed it.
+	Mode                 string          `json:"mode"`
+	LEDs                 layout.Zone     `json:"leds"`
+	Color                string          `json:"color"`
+	Velocity             bool            `json:"velocity,omitempty"` // Scale the color by velocity/127, for holds and effects without velocity.
+	Effect               string          `json:"effect,omitempty"`   // Trigger mode, same effects and options as custom presets.
+	Options              json.RawMessage `json:"options,omitempty"`
+	effects.LayerOptions                 // Trigger mode, held LEDs are on led.HELD_LAYER.
+}
+
+// Validate checks the groups on their own, zones are checked by DrumsMapper.Check.
+func (k *KitFile) Validate() error {
+	notes := make(map[uint8]string)
+	for _, group := range k.Groups {
+		if len(group.Notes) == 0 {
+			return fmt.Errorf("group %q: no notes", group.Name)
+		}
+		for _, note := range group.Notes {
+			if note > 127 {
+				return fmt.Errorf("group %q: note %d out of range", group.Name, note)
+			}
+			if other, ok := notes[note]; ok {
+				return fmt.Errorf("group %q: note %d already in group %q", group.Name, note, other)
+			}
+			notes[note] = group.Name
+		}
+		if err := group.LEDs.Validate(); err != nil {
+			return fmt.Errorf("group %q: leds %v", group.Name, err)
+		}
+		if err := group.validate(); err != nil {
+			return fmt.Errorf("group %q: %v", group.Name, err)
+		}
+	}
+	if k.Default != nil {
+		if err := k.Default.validate(); err != nil {
+			return fmt.Errorf("default: %v", err)
+		}
+	}
+	return nil
+}
+
+func (g *Group) validate() error {
+	if g.Mode != MODE_HOLD && g.Mode != MODE_TRIGGER {
+		return fmt.Errorf("mode must be %s or %s (got %q)", MODE_HOLD, MODE_TRIGGER, g.Mode)
+	}
+	if _, err := colorful.Hex(g.Color); err != nil {
+		return fmt.Errorf("invalid color %q", g.Color)
+	}
+	if g.Threshold > 127 {
+		return fmt.Errorf("threshold must be at most 127 (got %d)", g.Threshold)
+	}
+	if g.Mode == MODE_TRIGGER {
+		if err := custom.CheckEffect(g.Effect, g.Options); err != nil {
+			return err
+		}
+	}
+	return g.LayerOptions.Validate()
+}
+
+// kitGroup is a resolved group, its mapping creates the triggered effects.
+type kitGroup struct {
+	mode      string
+	threshold uint8
+	velocity  bool
+	mapping   custom.Mapping
+}
+
+type DrumsMapper struct {
+	sync.RWMutex
+	groups     map[uint8]*kitGroup
+	fallback   *kitGroup // Default group, its range is the note.
+	layout     *layout.Layout
+	kitsDir    string
+	currentKit string
+}
+
+// MapMessage maps a note of the drums channel to the group of the current kit.
+func (d *DrumsMapper) MapMessage(array led.LEDArray, message listener.MidiMessage) {
+	if message.Kind != listener.KIND_NOTE {
+		return
+	}
+	d.RLock()
+	group, ok := d.groups[message.Note]
+	if !ok && d.fallback != nil {
+		fallback := *d.fallback
+		fallback.mapping.Range = []int{int(message.Note)}
+		group, ok = &fallback, true
+	}
+	d.RUnlock()
+	if !ok || (message.On && message.Velocity <= group.threshold) {
+		return
+	}
+
+	color := group.mapping.Color
+	if group.velocity {
+		scale := float64(message.Velocity) / 127
+		color = colorful.Color{R: color.R * scale, G: color.G * scale, B: color.B * scale}
+	}
+	switch group.mode {
+	case MODE_HOLD:
+		r, g, b := color.Clamped().RGB255()
+		for _, ledNumber := range group.mapping.Range {
+			array.SetLED(ledNumber, message.On, r, g, b)
+		}
+	case MODE_TRIGGER:
+		if !message.On {
+			return
+		}
+		mapping := group.mapping
+		mapping.Color = color
+		effect, err := mapping.NewEffect(message.Velocity)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		array.SetLEDsEffect(effect)
+	}
+}
+
+func (d *DrumsMapper) LoadKitFromFile(filename string) error {
+	data, err := os.ReadFile(filepath.Join(d.kitsDir, filename))
+	if err != nil {
+		return err
+	}
+	var kit KitFile
+	if err := json.Unmarshal(data, &kit); err != nil {
+		return err
+	}
+	if err := kit.Validate(); err != nil {
+		return err
+	}
+	groups, fallback, err := d.resolve(&kit)
+	if err != nil {
+		return err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.groups, d.fallback = groups, fallback
+	log.Printf("Loaded drum kit '%s' with %d groups from %s\n", kit.Name, len(kit.Groups), filename)
+	return nil
+}
+
+// Check validates a kit and resolves the LEDs of its groups with the layout.
+func (d *DrumsMapper) Check(kit *KitFile) error {
+	if err := kit.Validate(); err != nil {
+		return err
+	}
+	_, _, err := d.resolve(kit)
+	return err
+}
+
+// resolve returns the groups of a kit by note and its default group, resolving their LEDs with the layout.
+func (d *DrumsMapper) resolve(kit *KitFile) (map[uint8]*kitGroup, *kitGroup, error) {
+	groups := make(map[uint8]*kitGroup)
+	for _, group := range kit.Groups {
+		ledRange, err := d.layout.Resolve(group.LEDs, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("group %q: %v", group.Name, err)
+		}
+		resolved := newKitGroup(group, ledRange)
+		for _, note := range group.Notes {
+			groups[note] = resolved
+		}
+	}
+	var fallback *kitGroup
+	if kit.Default != nil {
+		fallback = newKitGroup(*kit.Default, nil)
+	}
+	return groups, fallback, nil
+}
+
+func newKitGroup(group Group, ledRange []int) *kitGroup {
+	color, _ := colorful.Hex(group.Color) // Checked by KitFile.Validate.
+	return &kitGroup{
+		mode:      group.Mode,
+		threshold: group.Threshold,
+		velocity:  group.Velocity,
+		mapping: custom.Mapping{
+			Name:    group.Name,
+			Range:   ledRange,
+			Color:   color,
+			Effect:  group.Effect,
+			Options: group.Options,
+			Layer:   group.LayerOptions,
+		},
+	}
+}
+
+func (d *DrumsMapper) SwitchKit(filename string) error {
+	if err := d.LoadKitFromFile(filename); err != nil {
+		return err
+	}
+	d.Lock()
+	d.currentKit = filename
+	d.Unlock()
+	return nil
+}
+
+// CurrentKit returns the file name of the active drum kit.
+func (d *DrumsMapper) CurrentKit() string {
+	d.RLock()
+	defer d.RUnlock()
+	return d.currentKit
+}
+
+// KitsDir returns the directory drum kit files are loaded from.
+func (d *DrumsMapper) KitsDir() string {
+	return d.kitsDir
+}
+
+// NewDrumsMapper returns a mapper loading kit from the drums directory.
+func NewDrumsMapper(cfg *config.Config, pixelLayout *layout.Layout, kit string) *DrumsMapper {
+	mapper := &DrumsMapper{
+		groups:     make(map[uint8]*kitGroup),
+		layout:     pixelLayout,
+		kitsDir:    cfg.DrumsDir,
+		currentKit: kit,
+	}
+
+	// Load default kit on startup
+	if err := mapper.LoadKitFromFile(kit); err != nil {
+		log.Printf("Warning: Could not load drum kit '%s': %v\n", kit, err)
+	}
+	return mapper
+}
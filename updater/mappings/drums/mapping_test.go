@@ -0,0 +1,131 @@
+package drums_test
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/layout"
+	"ddp-sender/led"
+	"ddp-sender/listener"
+	"ddp-sender/updater/mappings/drums"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func newDrumsMapper(t *testing.T, kit string) *drums.DrumsMapper {
+	cfg := config.Default()
+	cfg.LEDAmount = 10
+	cfg.DrumsDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(cfg.DrumsDir, cfg.DrumKit), []byte(kit), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pixelLayout, err := layout.New(cfg.Layout, cfg.LEDAmount)
+	if err != nil {
+		t.Fatalf("layout.New() error = %v", err)
+	}
+	return drums.NewDrumsMapper(cfg, pixelLayout, cfg.DrumKit)
+}
+
+func TestDrumsMapper_MapMessage(t *testing.T) {
+	mapper := newDrumsMapper(t, `{
+		"name": "Test kit",
+		"groups": [
+			{"name": "Kick", "notes": [36], "threshold": 80, "mode": "hold", "leds": {"ranges": [{"first": 0, "last": 2}]}, "color": "#ff0000"},
+			{"name": "Hat", "notes": [42, 44], "mode": "hold", "leds": {"ranges": [{"first": 4, "last": 5}]}, "color": "#0000ff", "velocity": true},
+			{"name": "Crash", "notes": [49], "mode": "trigger", "leds": {"ranges": [{"first": 6, "last": 8}]}, "color": "#00ff00", "effect": "static"}
+		],
+		"default": {"mode": "hold", "color": "#ffffff"}
+	}`)
+	note := func(note, velocity uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: 2, Note: note, Velocity: velocity, On: velocity > 0}
+	}
+
+	tests := []struct {
+		name     string
+		messages []listener.MidiMessage
+		want     map[int]colorful.Color
+	}{
+		{"Hold above threshold", []listener.MidiMessage{note(36, 100)}, map[int]colorful.Color{0: {R: 1}, 1: {R: 1}, 2: {}}},
+		{"Below threshold ignored", []listener.MidiMessage{note(36, 80)}, map[int]colorful.Color{0: {}}},
+		{"Note off releases", []listener.MidiMessage{note(36, 100), note(36, 0)}, map[int]colorful.Color{0: {}, 1: {}}},
+		{"Velocity scales the color", []listener.MidiMessage{note(44, 127)}, map[int]colorful.Color{4: {B: 1}}},
+		{"Trigger starts an effect", []listener.MidiMessage{note(49, 127), note(49, 0)}, map[int]colorful.Color{6: {G: 1}, 7: {G: 1}}},
+		{"Default group on the note LED", []listener.MidiMessage{note(9, 100)}, map[int]colorful.Color{9: {R: 1, G: 1, B: 1}}},
+		{"Notes out of the canvas ignored", []listener.MidiMessage{note(100, 100)}, map[int]colorful.Color{9: {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			array := led.NewLEDArrayColor(10)
+			for _, message := range tt.messages {
+				mapper.MapMessage(array, message)
+			}
+			array.SetNextEffectValues()
+			frame := array.GetFrame(nil)
+			for i, want := range tt.want {
+				if !frame[i].AlmostEqualRgb(want) {
+					t.Errorf("LED %d = %v, want %v", i, frame[i], want)
+				}
+			}
+		})
+	}
+
+	// Half velocity halves a velocity scaled hold.
+	array := led.NewLEDArrayColor(10)
+	mapper.MapMessage(array, note(42, 64))
+	array.SetNextEffectValues()
+	if got, want := array.GetFrame(nil)[4], (colorful.Color{B: 64.0 / 127}); !got.AlmostEqualRgb(want) {
+		t.Errorf("LED 4 = %v, want %v", got, want)
+	}
+}
+
+func TestKitFile_Validate(t *testing.T) {
+	tests := map[string]string{
+		"no notes":               `{"groups": [{"name": "a", "mode": "hold", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "#fff"}]}`,
+		"duplicate note":         `{"groups": [{"name": "a", "notes": [1], "mode": "hold", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "#fff"}, {"name": "b", "notes": [1], "mode": "hold", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "#fff"}]}`,
+		"unknown mode":           `{"groups": [{"name": "a", "notes": [1], "mode": "flash", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "#fff"}]}`,
+		"invalid color":          `{"groups": [{"name": "a", "notes": [1], "mode": "hold", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "red"}]}`,
+		"empty leds":             `{"groups": [{"name": "a", "notes": [1], "mode": "hold", "leds": {}, "color": "#fff"}]}`,
+		"invalid default":        `{"groups": [], "default": {"mode": "hold", "color": "#fff", "blend": "screen"}}`,
+		"trigger without effect": `{"groups": [{"name": "a", "notes": [1], "mode": "trigger", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "#fff"}]}`,
+		"unknown effect":         `{"groups": [{"name": "a", "notes": [1], "mode": "trigger", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "#fff", "effect": "strobe"}]}`,
+		"sweep without options":  `{"groups": [{"name": "a", "notes": [1], "mode": "trigger", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "#fff", "effect": "sweep"}]}`,
+		"invalid options":        `{"groups": [{"name": "a", "notes": [1], "mode": "trigger", "leds": {"ranges": [{"first": 0, "last": 1}]}, "color": "#fff", "effect": "decay", "options": {"decay_coef": "fast"}}]}`,
+		"default trigger":        `{"groups": [], "default": {"mode": "trigger", "color": "#fff", "effect": "syncWalk"}}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			var kit drums.KitFile
+			if err := json.Unmarshal([]byte(data), &kit); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if err := kit.Validate(); err == nil {
+				t.Errorf("Validate() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestDrumsMapper_Check(t *testing.T) {
+	mapper := newDrumsMapper(t, `{"name": "Empty", "groups": []}`)
+
+	// The shipped default kit is valid.
+	data, err := os.ReadFile("../../../drums/default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kit drums.KitFile
+	if err := json.Unmarshal(data, &kit); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if err := mapper.Check(&kit); err != nil {
+		t.Errorf("Check(default.json) error = %v", err)
+	}
+
+	// Groups referencing unknown zones are rejected.
+	kit = drums.KitFile{Groups: []drums.Group{{Name: "a", Notes: []uint8{1}, Mode: drums.MODE_HOLD, LEDs: layout.Zone{Zones: []string{"missing"}}, Color: "#fff"}}}
+	if err := mapper.Check(&kit); err == nil {
+		t.Error("Check() with an unknown zone error = nil, want error")
+	}
+}
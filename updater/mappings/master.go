@@ -0,0 +1,29 @@
+package mappings
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/led"
+	"ddp-sender/listener"
+)
+
+// MasterMapping drives the master controls from the notes and controllers of the master channel.
+func MasterMapping(master led.MasterControl, cfg config.MasterConfig, message listener.MidiMessage) {
+	if message.Kind == listener.KIND_CONTROL_CHANGE && int(message.Controller) == cfg.BrightnessCC {
+		master.SetBrightness(float64(message.Value) / 127)
+		return
+	}
+	if message.Kind != listener.KIND_NOTE || !message.On {
+		return
+	}
+	state := master.MasterState()
+	switch int(message.Note) {
+	case cfg.BrightnessNote:
+		master.SetBrightness(float64(message.Velocity) / 127)
+	case cfg.BlackoutNote:
+		master.SetBlackout(!state.Blackout, 0)
+	case cfg.FadeNote:
+		master.SetBlackout(!state.Blackout, cfg.FadeTime.Duration())
+	case cfg.FreezeNote:
+		master.SetFreeze(!state.Frozen)
+	}
+}
@@ -0,0 +1,125 @@
+package updater
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/osc"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandleOSC maps an OSC message onto the custom mapper and the master controls:
+//
+//	/note/{n} [velocity]               triggers, or releases at velocity 0, the preset of note n
+//	/preset/{name}/trigger [velocity]  same for the preset named name
+//	/mapping/switch {file} [transition] [fade ms]
+//	                                   switches the mapping file, overriding the configured transition
+//	/master/brightness {level}
+//	/master/blackout {on} [fade ms]
+//	/master/freeze {on}
+//
+// Float levels and velocities are 0-1 (TouchOSC faders and buttons), integers 0-127 like MIDI.
+// A missing velocity triggers at full velocity.
+func (u *Updater) HandleOSC(message osc.Message) error {
+	parts := strings.Split(strings.TrimPrefix(message.Address, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "note":
+		note, err := strconv.ParseUint(parts[1], 10, 7)
+		if err != nil {
+			return fmt.Errorf("invalid note %q", parts[1])
+		}
+		return u.triggerOSC(uint8(note), message)
+	case len(parts) == 3 && parts[0] == "preset" && parts[2] == "trigger":
+		note, err := u.customMapper.PresetNote(parts[1])
+		if err != nil {
+			return err
+		}
+		return u.triggerOSC(note, message)
+	case message.Address == "/mapping/switch":
+		file, ok := message.String(0)
+		if !ok {
+			return fmt.Errorf("expected a mapping file name")
+		}
+		transition := u.customMapper.Transition()
+		if transitionType, ok := message.String(1); ok {
+			transition.Type = transitionType
+		}
+		fade, ok, err := oscMillis(message, 2)
+		if err != nil {
+			return err
+		}
+		if ok {
+			transition.Duration = config.Duration(fade)
+		}
+		return u.customMapper.SwitchMapping(file, &transition)
+	case len(parts) == 2 && parts[0] == "master":
+		if u.master == nil {
+			return fmt.Errorf("master controls unavailable")
+		}
+		level, ok := oscLevel(message, 0)
+		if !ok {
+			return fmt.Errorf("expected a number")
+		}
+		switch parts[1] {
+		case "brightness":
+			u.master.SetBrightness(level)
+		case "blackout":
+			fade, _, err := oscMillis(message, 1)
+			if err != nil {
+				return err
+			}
+			u.master.SetBlackout(level > 0, fade)
+		case "freeze":
+			u.master.SetFreeze(level > 0)
+		default:
+			return fmt.Errorf("unknown address")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown address")
+}
+
+// triggerOSC triggers or releases the preset of note with the velocity of message.
+func (u *Updater) triggerOSC(note uint8, message osc.Message) error {
+	level := 1.0
+	if len(message.Arguments) > 0 {
+		var ok bool
+		if level, ok = oscLevel(message, 0); !ok {
+			return fmt.Errorf("expected a velocity")
+		}
+	}
+	velocity := uint8(math.Round(level * 127))
+	if velocity == 0 {
+		return u.customMapper.TriggerPresetOff(note, 0)
+	}
+	return u.customMapper.TriggerPreset(note, velocity)
+}
+
+// oscLevel returns argument i as a 0-1 level, floats are levels and integers MIDI values.
+// Non-finite floats are rejected.
+func oscLevel(message osc.Message, i int) (float64, bool) {
+	value, ok := message.Number(i)
+	if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false
+	}
+	switch message.Arguments[i].(type) {
+	case int32, int64:
+		value /= 127
+	}
+	return max(0, min(1, value)), true
+}
+
+// oscMillis returns argument i as a duration in milliseconds, ok is false when it is missing.
+// Non-finite, negative and out of range numbers are rejected.
+func oscMillis(message osc.Message, i int) (time.Duration, bool, error) {
+	value, ok := message.Number(i)
+	if !ok {
+		return 0, false, nil
+	}
+	if math.IsNaN(value) || value < 0 || value > float64(math.MaxInt64/int64(time.Millisecond)) {
+		return 0, true, fmt.Errorf("invalid duration %v ms", value)
+	}
+	return time.Duration(math.Round(value * float64(time.Millisecond))), true, nil
+}
@@ -0,0 +1,153 @@
+package updater_test
+
+import (
+	"ddp-sender/layout"
+	"ddp-sender/led"
+	"ddp-sender/listener"
+	"ddp-sender/osc"
+	"ddp-sender/updater"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// newOSCUpdater returns an Updater with red.json, a "Red Flash" preset on note 110 lighting
+// LEDs 120-124, as the switchable mapping and the LED array as master control.
+func newOSCUpdater(t *testing.T) (*updater.Updater, *led.LEDArrayColor) {
+	cfg := testConfig(t)
+	cfg.DefaultMapping = "red.json"
+	data := `{"name": "Red", "presets": [{"name": "Red Flash", "note": 110, "first": 120, "last": 125, "step": 1, "color": "#ff0000", "effect": "static"}]}`
+	if err := os.WriteFile(filepath.Join(cfg.MappingsDir, "red.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeMapping(t, cfg, "blue.json", "#0000ff", 130)
+	pixelLayout, err := layout.New(cfg.Layout, cfg.LEDAmount)
+	if err != nil {
+		t.Fatalf("layout.New() error = %v", err)
+	}
+
+	array := led.NewLEDArrayColor(cfg.LEDAmount)
+	u := updater.NewUpdater(cfg, pixelLayout, array, make(chan listener.MidiMessage))
+	u.SetMasterControl(array)
+	return u, array
+}
+
+func TestUpdater_HandleOSC_Presets(t *testing.T) {
+	red := colorful.Color{R: 1}
+
+	tests := []struct {
+		name     string
+		messages []osc.Message
+		want     colorful.Color // LED 120
+	}{
+		{"Note at full float velocity", []osc.Message{{Address: "/note/110", Arguments: []any{float32(1)}}}, red},
+		{"Note without velocity", []osc.Message{{Address: "/note/110"}}, red},
+		{"Note released at velocity 0", []osc.Message{{Address: "/note/110"}, {Address: "/note/110", Arguments: []any{int32(0)}}}, colorful.Color{}},
+		{"Preset by name", []osc.Message{{Address: "/preset/Red Flash/trigger", Arguments: []any{int32(127)}}}, red},
+		{"Preset with underscores", []osc.Message{{Address: "/preset/Red_Flash/trigger"}}, red},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, array := newOSCUpdater(t)
+			for _, message := range tt.messages {
+				if err := u.HandleOSC(message); err != nil {
+					t.Fatalf("HandleOSC(%s) error = %v", message.Address, err)
+				}
+			}
+			array.SetNextEffectValues()
+			if got := array.GetFrame(nil)[120]; !got.AlmostEqualRgb(tt.want) {
+				t.Errorf("LED 120 = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdater_HandleOSC_ConcurrentWithMIDI(t *testing.T) {
+	cfg := testConfig(t)
+	writeMapping(t, cfg, "red.json", "#ff0000", 120)
+	cfg.DefaultMapping = "red.json"
+	u, _, send := runUpdater(t, cfg)
+
+	// The OSC server triggers from its own goroutine while MIDI notes are mapped.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			u.HandleOSC(osc.Message{Address: "/note/110", Arguments: []any{int32(127)}})
+			u.HandleOSC(osc.Message{Address: "/note/110", Arguments: []any{int32(0)}})
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		send(listener.MidiMessage{Channel: 3, Note: 110, Velocity: 127, On: true}, listener.MidiMessage{Channel: 3, Note: 110})
+	}
+	<-done
+}
+
+func TestUpdater_HandleOSC_MappingSwitch(t *testing.T) {
+	u, _ := newOSCUpdater(t)
+	if err := u.HandleOSC(osc.Message{Address: "/mapping/switch", Arguments: []any{"blue.json", "crossfade", int32(500)}}); err != nil {
+		t.Fatalf("HandleOSC() error = %v", err)
+	}
+	if got := u.GetCustomMapper().CurrentMapping(); got != "blue.json" {
+		t.Errorf("CurrentMapping() = %q, want blue.json", got)
+	}
+}
+
+func TestUpdater_HandleOSC_Master(t *testing.T) {
+	tests := []struct {
+		name    string
+		message osc.Message
+		want    led.MasterState
+	}{
+		{"Float brightness is a level", osc.Message{Address: "/master/brightness", Arguments: []any{float32(0.5)}}, led.MasterState{Brightness: 0.5, Level: 1}},
+		{"Integer brightness is a MIDI value", osc.Message{Address: "/master/brightness", Arguments: []any{int32(64)}}, led.MasterState{Brightness: 64.0 / 127, Level: 1}},
+		{"Brightness clamped", osc.Message{Address: "/master/brightness", Arguments: []any{float64(3)}}, led.MasterState{Brightness: 1, Level: 1}},
+		{"Instant blackout", osc.Message{Address: "/master/blackout", Arguments: []any{int32(1)}}, led.MasterState{Brightness: 1, Blackout: true}},
+		{"Faded blackout", osc.Message{Address: "/master/blackout", Arguments: []any{true, float32(60000)}}, led.MasterState{Brightness: 1, Blackout: true, Level: 1}},
+		{"Freeze", osc.Message{Address: "/master/freeze", Arguments: []any{float32(1)}}, led.MasterState{Brightness: 1, Level: 1, Frozen: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, array := newOSCUpdater(t)
+			if err := u.HandleOSC(tt.message); err != nil {
+				t.Fatalf("HandleOSC() error = %v", err)
+			}
+			got := array.MasterState()
+			if math.Abs(got.Brightness-tt.want.Brightness) > 1e-9 || got.Blackout != tt.want.Blackout ||
+				math.Abs(got.Level-tt.want.Level) > 0.01 || got.Frozen != tt.want.Frozen {
+				t.Errorf("MasterState() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdater_HandleOSC_Errors(t *testing.T) {
+	for _, message := range []osc.Message{
+		{Address: "/note/200"},
+		{Address: "/note/110", Arguments: []any{"loud"}},
+		{Address: "/preset/Missing/trigger"},
+		{Address: "/mapping/switch"},
+		{Address: "/mapping/switch", Arguments: []any{"missing.json"}},
+		{Address: "/mapping/switch", Arguments: []any{"blue.json", "dissolve"}},
+		{Address: "/mapping/switch", Arguments: []any{"blue.json", "crossfade", float32(math.NaN())}},
+		{Address: "/mapping/switch", Arguments: []any{"blue.json", "crossfade", int32(-1)}},
+		{Address: "/mapping/switch", Arguments: []any{"blue.json", "crossfade", math.Inf(1)}},
+		{Address: "/master/brightness", Arguments: []any{float32(math.NaN())}},
+		{Address: "/master/brightness", Arguments: []any{math.Inf(1)}},
+		{Address: "/master/blackout", Arguments: []any{int32(1), float32(math.NaN())}},
+		{Address: "/master/blackout", Arguments: []any{int32(1), math.Inf(1)}},
+		{Address: "/master/dim", Arguments: []any{int32(1)}},
+		{Address: "/unknown"},
+	} {
+		u, array := newOSCUpdater(t)
+		if err := u.HandleOSC(message); err == nil {
+			t.Errorf("HandleOSC(%s %v) error = nil, want error", message.Address, message.Arguments)
+		}
+		if state := array.MasterState(); state.Brightness != 1 || state.Blackout {
+			t.Errorf("HandleOSC(%s %v) changed the master state to %+v", message.Address, message.Arguments, state)
+		}
+	}
+}
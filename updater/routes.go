@@ -0,0 +1,85 @@
+package updater
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/layout"
+	"ddp-sender/led"
+	"ddp-sender/listener"
+	"ddp-sender/updater/mappings"
+	"ddp-sender/updater/mappings/custom"
+	"ddp-sender/updater/mappings/drums"
+)
+
+// Handler maps the messages of its routes to the LED array.
+type Handler interface {
+	MapMessage(array led.LEDArray, message listener.MidiMessage)
+}
+
+// HandlerFunc adapts a function to Handler.
+type HandlerFunc func(array led.LEDArray, message listener.MidiMessage)
+
+func (f HandlerFunc) MapMessage(array led.LEDArray, message listener.MidiMessage) {
+	f(array, message)
+}
+
+type route struct {
+	config.RouteConfig
+	handlers []Handler
+}
+
+// matches reports whether message is on the channel of the route and, for notes and
+// poly aftertouch, in its note range.
+func (r *route) matches(message listener.MidiMessage) bool {
+	if int(message.Channel) != r.Channel {
+		return false
+	}
+	if r.Notes == nil {
+		return true
+	}
+	switch message.Kind {
+	case listener.KIND_NOTE, listener.KIND_POLY_AFTERTOUCH:
+		return int(message.Note) >= r.Notes.Low && int(message.Note) <= r.Notes.High
+	}
+	return true
+}
+
+// buildRoutes resolves the handlers of the configured routes. Handlers naming a file get
+// their own mapper, created once per file, which program changes do not switch.
+func (u *Updater) buildRoutes(cfg *config.Config, pixelLayout *layout.Layout) {
+	customMappers := make(map[string]*custom.CustomMapper)
+	drumsMappers := map[string]*drums.DrumsMapper{"": u.drumsMapper}
+	u.mappers = []*custom.CustomMapper{u.customMapper}
+
+	for _, routeConfig := range cfg.Routes {
+		r := route{RouteConfig: routeConfig}
+		for _, handlerConfig := range routeConfig.Handlers {
+			switch handlerConfig.Type {
+			case config.HANDLER_DIRECT:
+				r.handlers = append(r.handlers, HandlerFunc(mappings.DirectMapping))
+			case config.HANDLER_DRUMS:
+				mapper, ok := drumsMappers[handlerConfig.File]
+				if !ok {
+					mapper = drums.NewDrumsMapper(cfg, pixelLayout, handlerConfig.File)
+					drumsMappers[handlerConfig.File] = mapper
+				}
+				r.handlers = append(r.handlers, mapper)
+			case config.HANDLER_MAPPING:
+				if handlerConfig.File == "" {
+					// The active mappings without channels follow the switchable one.
+					r.handlers = append(r.handlers, HandlerFunc(u.mapSwitchable))
+					continue
+				}
+				mapper, ok := customMappers[handlerConfig.File]
+				if !ok {
+					mapper = custom.NewCustomMapper(cfg, pixelLayout, handlerConfig.File)
+					mapper.SetLEDArray(u.array)
+					customMappers[handlerConfig.File] = mapper
+					u.mappers = append(u.mappers, mapper)
+				}
+				// Bound to its file, it ignores program changes like the activated mappings.
+				r.handlers = append(r.handlers, &activeMapping{ActiveMapping: ActiveMapping{File: handlerConfig.File, Routed: true}, mapper: mapper})
+			}
+		}
+		u.routes = append(u.routes, r)
+	}
+}
@@ -1,31 +1,37 @@
 package updater
 
 import (
+	"context"
+	"ddp-sender/config"
+	"ddp-sender/layout"
 	"ddp-sender/led"
 	"ddp-sender/listener"
 	"ddp-sender/updater/effects"
 	"ddp-sender/updater/mappings"
 	"ddp-sender/updater/mappings/custom"
+	"ddp-sender/updater/mappings/drums"
 	"ddp-sender/util"
 	"log"
-	"time"
+	"sync"
 
 	"github.com/lucasb-eyer/go-colorful"
 )
 
 type Updater struct {
 	array        led.LEDArray
+	master       led.MasterControl
+	masterConfig config.MasterConfig
 	sendChannel  chan listener.MidiMessage
 	customMapper *custom.CustomMapper
+	drumsMapper  *drums.DrumsMapper
+	routes       []route
+	mappers      []*custom.CustomMapper // Every custom mapper of the routes, the switchable one first.
+	activeMutex  sync.RWMutex
+	active       []*activeMapping
 }
 
-func (u *Updater) Ticker(refreshRate time.Duration) {
-	for range time.Tick(20 * time.Millisecond) {
-		u.array.SetNextEffectValues()
-	}
-}
-
-func (u *Updater) Run() {
+// Run maps incoming MIDI messages to the LED array until ctx is cancelled.
+func (u *Updater) Run(ctx context.Context) {
 	log.Println("Launched LedUpdater")
 
 	// u.array.SetLEDsEffect(60, 80, 255, 127, 0, &effects.Decay{DecayCoef: 2})
@@ -59,34 +65,62 @@ func (u *Updater) Run() {
 		},
 	})
 
-	// Setup custom mapper listener (to receive custom presets from REAPER)
-	go u.customMapper.RunListener()
+	for {
+		var message listener.MidiMessage
+		select {
+		case message = <-u.sendChannel:
+		case <-ctx.Done():
+			return
+		}
+
+		if message.Kind.IsRealtime() {
+			// Transport messages have no channel, every custom mapper follows the song.
+			for _, mapper := range u.mappers {
+				mapper.MapMessage(u.array, message)
+			}
+			u.mapActive(message)
+			continue
+		}
+
+		if u.master != nil && u.masterConfig.Channel != 0 && int(message.Channel) == u.masterConfig.Channel {
+			mappings.MasterMapping(u.master, u.masterConfig, message)
+			continue
+		}
 
-	for message := range u.sendChannel {
-		switch message.Channel {
-		case 1:
-			// Individual LED mapping
-			u.array.SetLED(int(message.Note), message.On, message.Velocity, 0, 0)
-		case 2:
-			// Drums fixed mapping
-			mappings.DrumsMapping(u.array, message)
-		case 3:
-			// Dynamic mapping from REAPER
-			u.customMapper.MapMessage(u.array, message)
+		for i := range u.routes {
+			if u.routes[i].matches(message) {
+				for _, handler := range u.routes[i].handlers {
+					handler.MapMessage(u.array, message)
+				}
+			}
 		}
+		u.mapActive(message)
 	}
 }
 
-func NewUpdater(array led.LEDArray, sendChannel chan listener.MidiMessage) *Updater {
-	customMapper := custom.NewCustomMapper()
+func NewUpdater(cfg *config.Config, pixelLayout *layout.Layout, array led.LEDArray, sendChannel chan listener.MidiMessage) *Updater {
+	customMapper := custom.NewCustomMapper(cfg, pixelLayout, cfg.DefaultMapping)
 	customMapper.SetLEDArray(array)
-	return &Updater{
+	u := &Updater{
 		array:        array,
+		masterConfig: cfg.Master,
 		sendChannel:  sendChannel,
 		customMapper: customMapper,
+		drumsMapper:  drums.NewDrumsMapper(cfg, pixelLayout, cfg.DrumKit),
 	}
+	u.buildRoutes(cfg, pixelLayout)
+	return u
+}
+
+// SetMasterControl enables the master channel notes configured in config.MasterConfig.
+func (u *Updater) SetMasterControl(master led.MasterControl) {
+	u.master = master
 }
 
 func (u *Updater) GetCustomMapper() *custom.CustomMapper {
 	return u.customMapper
 }
+
+func (u *Updater) GetDrumsMapper() *drums.DrumsMapper {
+	return u.drumsMapper
+}
@@ -0,0 +1,418 @@
+package updater_test
+
+import (
+	"context"
+	"ddp-sender/config"
+	"ddp-sender/layout"
+	"ddp-sender/led"
+	"ddp-sender/listener"
+	"ddp-sender/updater"
+	"ddp-sender/updater/mappings/custom"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// testConfig returns the default configuration with an empty mappings directory.
+func testConfig(t *testing.T) *config.Config {
+	cfg := config.Default()
+	cfg.MappingsDir = t.TempDir()
+	cfg.DrumsDir = "../drums" // The shipped default kit.
+	return cfg
+}
+
+// runUpdater runs an Updater and returns a function sending messages to it, which
+// returns once they are handled.
+func runUpdater(t *testing.T, cfg *config.Config) (*updater.Updater, *led.LEDArrayColor, func(messages ...listener.MidiMessage)) {
+	pixelLayout, err := layout.New(cfg.Layout, cfg.LEDAmount)
+	if err != nil {
+		t.Fatalf("layout.New() error = %v", err)
+	}
+
+	array := led.NewLEDArrayColor(cfg.LEDAmount)
+	sendChannel := make(chan listener.MidiMessage)
+	u := updater.NewUpdater(cfg, pixelLayout, array, sendChannel)
+	u.SetMasterControl(array) // Only used when cfg.Master.Channel is set.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		u.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return u, array, func(messages ...listener.MidiMessage) {
+		// The channel is unbuffered: once the unrouted channel 16 message is received,
+		// the messages before it are handled.
+		for _, message := range append(messages, listener.MidiMessage{Channel: 16}) {
+			sendChannel <- message
+		}
+	}
+}
+
+// writeMapping writes a mapping lighting 5 LEDs from first in color on note 110. Note 110
+// and LEDs 110-140 are clear of the demo effects started by Run.
+func writeMapping(t *testing.T, cfg *config.Config, name, color string, first int) {
+	data := fmt.Sprintf(`{"name": %q, "presets": [{"note": 110, "first": %d, "last": %d, "step": 1, "color": %q, "effect": "static"}]}`, name, first, first+5, color)
+	if err := os.WriteFile(filepath.Join(cfg.MappingsDir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdater_Run_HeldLEDs(t *testing.T) {
+	note := func(channel, note, velocity uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: channel, Note: note, Velocity: velocity, On: velocity > 0}
+	}
+	red := colorful.Color{R: 1}
+
+	tests := []struct {
+		name     string
+		messages []listener.MidiMessage
+		want     map[int]colorful.Color
+	}{
+		{
+			name:     "Direct note held",
+			messages: []listener.MidiMessage{note(1, 120, 127)},
+			want:     map[int]colorful.Color{119: {}, 120: {R: 1}, 121: {}},
+		},
+		{
+			name:     "Direct note released",
+			messages: []listener.MidiMessage{note(1, 120, 127), note(1, 120, 0)},
+			want:     map[int]colorful.Color{120: {}},
+		},
+		{
+			name:     "Drums hit held",
+			messages: []listener.MidiMessage{note(2, 36, 100)},
+			want:     map[int]colorful.Color{29: {}, 30: red, 44: red, 45: {}},
+		},
+		{
+			name:     "Drums hit released",
+			messages: []listener.MidiMessage{note(2, 36, 100), note(2, 36, 0)},
+			want:     map[int]colorful.Color{30: {}, 44: {}},
+		},
+		{
+			name:     "Drums and direct notes together",
+			messages: []listener.MidiMessage{note(2, 36, 100), note(1, 40, 51)},
+			want:     map[int]colorful.Color{39: red, 40: {R: 0.4}, 41: red},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, array, send := runUpdater(t, testConfig(t))
+			send(tt.messages...)
+
+			// Held LEDs survive every frame until released.
+			for frame := 0; frame < 5; frame++ {
+				array.SetNextEffectValues()
+				composed := array.GetFrame(nil)
+				for i, want := range tt.want {
+					if !composed[i].AlmostEqualRgb(want) {
+						t.Errorf("Frame %d LED %d = %v, want %v", frame, i, composed[i], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUpdater_Run_Routes(t *testing.T) {
+	note := func(channel, note uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: channel, Note: note, Velocity: 127, On: true}
+	}
+	cfg := testConfig(t)
+	writeMapping(t, cfg, "red.json", "#ff0000", 120)
+	writeMapping(t, cfg, "blue.json", "#0000ff", 130)
+	cfg.Programs = []string{"blue.json"}
+	cfg.Routes = []config.RouteConfig{
+		{Channel: 4, Handlers: []config.HandlerConfig{{Type: config.HANDLER_MAPPING, File: "red.json"}}},
+		{Channel: 5, Handlers: []config.HandlerConfig{{Type: config.HANDLER_MAPPING, File: "blue.json"}}},
+		{Channel: 6, Notes: &config.NoteRange{Low: 110, High: 127}, Handlers: []config.HandlerConfig{
+			{Type: config.HANDLER_MAPPING, File: "red.json"},
+			{Type: config.HANDLER_DIRECT},
+		}},
+	}
+	direct := colorful.Color{R: 1}
+
+	tests := []struct {
+		name     string
+		messages []listener.MidiMessage
+		want     map[int]colorful.Color
+	}{
+		{
+			name:     "Two channels drive their own mapping",
+			messages: []listener.MidiMessage{note(4, 110), note(5, 110)},
+			want:     map[int]colorful.Color{120: {R: 1}, 130: {B: 1}, 110: {}},
+		},
+		{
+			name:     "Several handlers on a route",
+			messages: []listener.MidiMessage{note(6, 110)},
+			want:     map[int]colorful.Color{120: {R: 1}, 130: {}, 110: direct},
+		},
+		{
+			name:     "Notes out of the route range dropped",
+			messages: []listener.MidiMessage{note(6, 109)},
+			want:     map[int]colorful.Color{120: {}, 109: {}},
+		},
+		{
+			name:     "Program changes ignored by file-bound mappings",
+			messages: []listener.MidiMessage{{Channel: 4, Kind: listener.KIND_PROGRAM_CHANGE, Value: 0}, note(4, 110)},
+			want:     map[int]colorful.Color{120: {R: 1}, 130: {}},
+		},
+		{
+			name:     "Channels of no route dropped",
+			messages: []listener.MidiMessage{note(1, 110), note(3, 110)},
+			want:     map[int]colorful.Color{120: {}, 110: {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, array, send := runUpdater(t, cfg)
+			send(tt.messages...)
+
+			array.SetNextEffectValues()
+			composed := array.GetFrame(nil)
+			for i, want := range tt.want {
+				if !composed[i].AlmostEqualRgb(want) {
+					t.Errorf("LED %d = %v, want %v", i, composed[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdater_Run_ChannelMessages(t *testing.T) {
+	note := listener.MidiMessage{Channel: 3, Note: 110, Velocity: 127, On: true}
+	cc := func(controller uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: 3, Kind: listener.KIND_CONTROL_CHANGE, Controller: controller}
+	}
+	poly := func(note, value uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: 3, Kind: listener.KIND_POLY_AFTERTOUCH, Note: note, Value: value}
+	}
+	pressure := func(value uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: 3, Kind: listener.KIND_CHANNEL_AFTERTOUCH, Value: value}
+	}
+	red := colorful.Color{R: 1}
+
+	tests := []struct {
+		name     string
+		messages []listener.MidiMessage
+		want     colorful.Color // LED 120
+	}{
+		{"Poly aftertouch dims its note", []listener.MidiMessage{note, poly(110, 0)}, colorful.Color{}},
+		{"Poly aftertouch of another note ignored", []listener.MidiMessage{note, poly(111, 0)}, red},
+		{"Channel aftertouch dims every effect", []listener.MidiMessage{note, pressure(0)}, colorful.Color{}},
+		{"Channel aftertouch keeps the effect", []listener.MidiMessage{note, pressure(0), pressure(127)}, red},
+		{"All sound off", []listener.MidiMessage{note, cc(custom.CC_ALL_SOUND_OFF)}, colorful.Color{}},
+		{"All notes off", []listener.MidiMessage{note, cc(custom.CC_ALL_NOTES_OFF)}, colorful.Color{}},
+		{"Other controllers ignored", []listener.MidiMessage{note, cc(1)}, red},
+		{"Transport stop", []listener.MidiMessage{note, {Kind: listener.KIND_STOP}}, colorful.Color{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			writeMapping(t, cfg, "red.json", "#ff0000", 120)
+			cfg.DefaultMapping = "red.json"
+			_, array, send := runUpdater(t, cfg)
+			send(tt.messages...)
+
+			array.SetNextEffectValues()
+			if got := array.GetFrame(nil)[120]; !got.AlmostEqualRgb(tt.want) {
+				t.Errorf("LED 120 = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdater_Run_MasterBrightnessCC(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Master.Channel = 15
+	_, array, send := runUpdater(t, cfg)
+	volume := func(channel uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: channel, Kind: listener.KIND_CONTROL_CHANGE, Controller: uint8(cfg.Master.BrightnessCC), Value: 64}
+	}
+
+	// The controller of another channel is routed instead.
+	send(volume(1))
+	if got := array.MasterState().Brightness; got != 1 {
+		t.Errorf("Brightness after a channel 1 controller = %v, want 1", got)
+	}
+	send(volume(15))
+	if got := array.MasterState().Brightness; got != 64.0/127 {
+		t.Errorf("Brightness = %v, want %v", got, 64.0/127)
+	}
+}
+
+func TestUpdater_Run_FinishEndsSyncWalks(t *testing.T) {
+	cfg := testConfig(t)
+	data := `{"name": "Walk", "presets": [{"note": 110, "first": 120, "last": 125, "step": 1, "color": "#ff0000", "effect": "syncWalk", "options": {"amount": 2}}]}`
+	if err := os.WriteFile(filepath.Join(cfg.MappingsDir, "walk.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeMapping(t, cfg, "blue.json", "#0000ff", 130)
+	cfg.DefaultMapping = "walk.json"
+	cfg.Programs = []string{"walk.json", "blue.json"}
+	cfg.Transition = config.TransitionConfig{Type: config.TRANSITION_FINISH}
+	_, array, send := runUpdater(t, cfg)
+
+	send(listener.MidiMessage{Channel: 3, Note: 110, Velocity: 127, On: true})
+	array.SetNextEffectValues()
+	if got := array.GetFrame(nil)[120]; !got.AlmostEqualRgb(colorful.Color{R: 1}) {
+		t.Fatalf("LED 120 before the switch = %v, want red", got)
+	}
+
+	// The walk waits for its next note, which the new mapping never sends.
+	send(listener.MidiMessage{Channel: 3, Kind: listener.KIND_PROGRAM_CHANGE, Value: 1})
+	for frame := 0; frame < 3; frame++ {
+		array.SetNextEffectValues()
+		if got := array.GetFrame(nil)[120]; !got.AlmostEqualRgb(colorful.Color{}) {
+			t.Errorf("Frame %d LED 120 after the switch = %v, want black", frame, got)
+		}
+	}
+}
+
+func TestUpdater_ActivateMapping(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.DefaultMapping = "blue.json"
+	writeMapping(t, cfg, "blue.json", "#0000ff", 130)
+	writeMapping(t, cfg, "red.json", "#ff0000", 120)
+	writeMapping(t, cfg, "green.json", "#00ff00", 130)
+	writeMapping(t, cfg, "white.json", "#ffffff", 110)
+	cfg.Routes = append(cfg.Routes, config.RouteConfig{Channel: 6, Handlers: []config.HandlerConfig{{Type: config.HANDLER_MAPPING, File: "white.json"}}})
+	u, array, send := runUpdater(t, cfg)
+	note := func(channel uint8) listener.MidiMessage {
+		return listener.MidiMessage{Channel: channel, Note: 110, Velocity: 127, On: true}
+	}
+	layer := func(layer int) *int { return &layer }
+	check := func(want map[int]colorful.Color) {
+		t.Helper()
+		array.SetNextEffectValues()
+		composed := array.GetFrame(nil)
+		for i, want := range want {
+			if !composed[i].AlmostEqualRgb(want) {
+				t.Errorf("LED %d = %v, want %v", i, composed[i], want)
+			}
+		}
+	}
+
+	// A mapping bound to its own channel.
+	if err := u.ActivateMapping(updater.ActiveMapping{File: "red.json", Channels: []int{5}}); err != nil {
+		t.Fatalf("ActivateMapping() error = %v", err)
+	}
+	send(note(5))
+	check(map[int]colorful.Color{120: {R: 1}, 130: {}})
+
+	// A mapping following the switchable one, below it.
+	if err := u.ActivateMapping(updater.ActiveMapping{File: "green.json", Layer: layer(-1)}); err != nil {
+		t.Fatalf("ActivateMapping() error = %v", err)
+	}
+	send(note(3))
+	check(map[int]colorful.Color{120: {R: 1}, 130: {B: 1}})
+
+	// Activating it again above the switchable mapping replaces it.
+	if err := u.ActivateMapping(updater.ActiveMapping{File: "green.json", Layer: layer(1)}); err != nil {
+		t.Fatalf("ActivateMapping() error = %v", err)
+	}
+	send(note(3))
+	check(map[int]colorful.Color{130: {G: 1}})
+
+	want := []updater.ActiveMapping{
+		{File: "white.json", Channels: []int{6}, Routed: true},
+		{File: "red.json", Channels: []int{5}},
+		{File: "green.json", Channels: []int{}, Layer: layer(1)},
+	}
+	if got := u.ActiveMappings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ActiveMappings() = %+v, want %+v", got, want)
+	}
+
+	// Deactivating ends the effects of the mapping.
+	if err := u.DeactivateMapping("green.json"); err != nil {
+		t.Fatalf("DeactivateMapping() error = %v", err)
+	}
+	check(map[int]colorful.Color{120: {R: 1}, 130: {B: 1}})
+
+	for name, mapping := range map[string]updater.ActiveMapping{
+		"missing file":    {File: "missing.json"},
+		"invalid channel": {File: "red.json", Channels: []int{17}},
+		"routed file":     {File: "white.json"},
+	} {
+		if err := u.ActivateMapping(mapping); err == nil {
+			t.Errorf("ActivateMapping() with a %s error = nil, want error", name)
+		}
+	}
+	if err := u.DeactivateMapping("green.json"); err == nil {
+		t.Error("DeactivateMapping() of an inactive mapping error = nil, want error")
+	}
+}
+
+func TestUpdater_ActiveMappingsConcurrentWithMIDI(t *testing.T) {
+	cfg := testConfig(t)
+	writeMapping(t, cfg, "red.json", "#ff0000", 120)
+	writeMapping(t, cfg, "blue.json", "#0000ff", 130)
+	cfg.DefaultMapping = "red.json"
+	u, _, send := runUpdater(t, cfg)
+	if err := u.ActivateMapping(updater.ActiveMapping{File: "blue.json", Channels: []int{5}}); err != nil {
+		t.Fatalf("ActivateMapping() error = %v", err)
+	}
+
+	// The web UI reloads and replaces the active mapping while its channel is mapped.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if err := u.ReloadMapping("blue.json"); err != nil {
+				t.Errorf("ReloadMapping() error = %v", err)
+			}
+			if err := u.ActivateMapping(updater.ActiveMapping{File: "blue.json", Channels: []int{5}}); err != nil {
+				t.Errorf("ActivateMapping() error = %v", err)
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		send(listener.MidiMessage{Channel: 5, Note: 110, Velocity: 127, On: true}, listener.MidiMessage{Channel: 5, Note: 110})
+	}
+	<-done
+}
+
+func TestUpdater_Run_MappingTransitions(t *testing.T) {
+	note := listener.MidiMessage{Channel: 3, Note: 110, Velocity: 127, On: true}
+	program := listener.MidiMessage{Channel: 3, Kind: listener.KIND_PROGRAM_CHANGE, Value: 1}
+
+	// Durations of an hour keep the levels still during the test.
+	tests := []struct {
+		transition config.TransitionConfig
+		want       map[int]colorful.Color
+	}{
+		{config.TransitionConfig{Type: config.TRANSITION_CUT}, map[int]colorful.Color{120: {}, 130: {B: 1}}},
+		{config.TransitionConfig{Type: config.TRANSITION_FINISH}, map[int]colorful.Color{120: {}, 130: {B: 1}}},
+		{config.TransitionConfig{Type: config.TRANSITION_CROSSFADE, Duration: config.Duration(time.Hour)}, map[int]colorful.Color{120: {R: 1}, 130: {}}},
+		{config.TransitionConfig{Type: config.TRANSITION_BLACK, Duration: config.Duration(time.Hour)}, map[int]colorful.Color{120: {R: 1}, 130: {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.transition.Type, func(t *testing.T) {
+			cfg := testConfig(t)
+			writeMapping(t, cfg, "red.json", "#ff0000", 120)
+			writeMapping(t, cfg, "blue.json", "#0000ff", 130)
+			cfg.DefaultMapping = "red.json"
+			cfg.Programs = []string{"red.json", "blue.json"}
+			cfg.Transition = tt.transition
+			_, array, send := runUpdater(t, cfg)
+
+			send(note, program, note)
+			array.SetNextEffectValues()
+			composed := array.GetFrame(nil)
+			for i, want := range tt.want {
+				if !composed[i].AlmostEqualRgb(want) {
+					t.Errorf("LED %d = %v, want %v", i, composed[i], want)
+				}
+			}
+		})
+	}
+}
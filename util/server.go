@@ -0,0 +1,24 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ServeHTTP runs server until ctx is cancelled, then shuts it down gracefully.
+func ServeHTTP(ctx context.Context, server *http.Server) error {
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
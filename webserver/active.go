@@ -0,0 +1,57 @@
+package webserver
+
+import (
+	"ddp-sender/updater"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// handleActiveMappings lists the mappings active next to the current one and activates them.
+func (ws *WebServer) handleActiveMappings(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ws.updater.ActiveMappings())
+		return
+	}
+
+	var request updater.ActiveMapping
+	if !decodePost(w, r, &request) {
+		return
+	}
+	if request.File == "" {
+		http.Error(w, "Missing mapping file", http.StatusBadRequest)
+		return
+	}
+	if !strings.HasSuffix(request.File, ".json") {
+		request.File += ".json"
+	}
+	if err := ws.updater.ActivateMapping(request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ws.updater.ActiveMappings())
+}
+
+// handleDeactivateMapping deactivates the mapping named in the path.
+func (ws *WebServer) handleDeactivateMapping(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	mappingName := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/activeMappings/"), "/")[0]
+	if mappingName == "" {
+		http.Error(w, "Missing mapping name", http.StatusBadRequest)
+		return
+	}
+	if !strings.HasSuffix(mappingName, ".json") {
+		mappingName += ".json"
+	}
+	if err := ws.updater.DeactivateMapping(mappingName); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ws.updater.ActiveMappings())
+}
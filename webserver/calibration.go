@@ -0,0 +1,120 @@
+package webserver
+
+import (
+	"ddp-sender/config"
+	"ddp-sender/updater/effects"
+	"ddp-sender/util"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	TEST_PATTERN_GRAY_RAMP = "gray-ramp" // Black to white across the canvas.
+	TEST_PATTERN_PRIMARIES = "primaries" // Red, green, blue and white quarters.
+	TEST_PATTERN_OFF       = "off"
+)
+
+// Test patterns are drawn above every mapping layer.
+const TEST_PATTERN_LAYER = math.MaxInt32
+
+type CalibrationResponse struct {
+	Profiles []string          `json:"profiles"`
+	Outputs  map[string]string `json:"outputs"` // Output name -> profile name.
+}
+
+type TestPatternRequest struct {
+	Pattern string `json:"pattern"`
+}
+
+func (ws *WebServer) handleCalibration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := CalibrationResponse{Outputs: make(map[string]string)}
+	for name := range config.BuiltinCalibrations() {
+		response.Profiles = append(response.Profiles, name)
+	}
+	for name := range ws.cfg.Calibrations {
+		if _, builtin := config.BuiltinCalibrations()[name]; !builtin {
+			response.Profiles = append(response.Profiles, name)
+		}
+	}
+	sort.Strings(response.Profiles)
+	for _, output := range ws.cfg.ResolvedOutputs() {
+		response.Outputs[output.Name] = output.Calibration
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleTestPattern replaces every running effect with a calibration test pattern,
+// so each output shows it through its own calibration profile.
+func (ws *WebServer) handleTestPattern(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request TestPatternRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+
+	var colors []colorful.Color
+	switch request.Pattern {
+	case TEST_PATTERN_GRAY_RAMP:
+		colors = grayRamp(ws.cfg.LEDAmount)
+	case TEST_PATTERN_PRIMARIES:
+		colors = primaries(ws.cfg.LEDAmount)
+	case TEST_PATTERN_OFF:
+	default:
+		http.Error(w, fmt.Sprintf("Unknown test pattern %q", request.Pattern), http.StatusBadRequest)
+		return
+	}
+
+	ws.patternMutex.Lock()
+	defer ws.patternMutex.Unlock()
+	if ws.testPattern != nil {
+		ws.testPattern.SetDone()
+		ws.testPattern = nil
+	}
+	if colors != nil {
+		ws.customMapper.ClearAllEffects()
+		ws.testPattern = effects.NewPattern(util.MakeRange(0, ws.cfg.LEDAmount, 1), colors)
+		ws.testPattern.SetLayer(effects.LayerOptions{Layer: TEST_PATTERN_LAYER})
+		ws.ledArray.SetLEDsEffect(ws.testPattern)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"pattern": request.Pattern})
+}
+
+func grayRamp(amount int) []colorful.Color {
+	colors := make([]colorful.Color, amount)
+	for i := range colors {
+		value := 1.0
+		if amount > 1 {
+			value = float64(i) / float64(amount-1)
+		}
+		colors[i] = colorful.Color{R: value, G: value, B: value}
+	}
+	return colors
+}
+
+func primaries(amount int) []colorful.Color {
+	blocks := []colorful.Color{{R: 1}, {G: 1}, {B: 1}, {R: 1, G: 1, B: 1}}
+	colors := make([]colorful.Color, amount)
+	for i := range colors {
+		colors[i] = blocks[i*len(blocks)/amount]
+	}
+	return colors
+}
@@ -0,0 +1,165 @@
+package webserver
+
+import (
+	"ddp-sender/updater/mappings/drums"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type DrumKitListItem struct {
+	Name         string `json:"name"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	GroupCount   int    `json:"groupCount"`
+	LastModified string `json:"lastModified"`
+	IsActive     bool   `json:"isActive"`
+}
+
+type SwitchDrumKitRequest struct {
+	File string `json:"file"`
+}
+
+func (ws *WebServer) handleDrumKits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	files, err := os.ReadDir(ws.drumsMapper.KitsDir())
+	if err != nil {
+		http.Error(w, "Failed to read drums directory", http.StatusInternalServerError)
+		return
+	}
+
+	kits := []DrumKitListItem{}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(ws.drumsMapper.KitsDir(), file.Name()))
+		if err != nil {
+			log.Printf("Error reading drum kit %s: %v", file.Name(), err)
+			continue
+		}
+		var kit drums.KitFile
+		if err := json.Unmarshal(data, &kit); err != nil {
+			log.Printf("Error parsing drum kit %s: %v", file.Name(), err)
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			log.Printf("Error getting file info for %s: %v", file.Name(), err)
+			continue
+		}
+
+		kits = append(kits, DrumKitListItem{
+			Name:         file.Name(),
+			Title:        kit.Name,
+			Description:  kit.Description,
+			GroupCount:   len(kit.Groups),
+			LastModified: info.ModTime().Format("2006-01-02"),
+			IsActive:     file.Name() == ws.drumsMapper.CurrentKit(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kits)
+}
+
+func (ws *WebServer) handleDrumKitOperations(w http.ResponseWriter, r *http.Request) {
+	kitName := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/drums/"), "/")[0]
+	if kitName == "" {
+		http.Error(w, "Missing drum kit name", http.StatusBadRequest)
+		return
+	}
+	if !strings.HasSuffix(kitName, ".json") {
+		kitName += ".json"
+	}
+	filePath := filepath.Join(ws.drumsMapper.KitsDir(), kitName)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				http.Error(w, "Drum kit not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to read drum kit", http.StatusInternalServerError)
+			}
+			return
+		}
+		var kit drums.KitFile
+		if err := json.Unmarshal(data, &kit); err != nil {
+			http.Error(w, "Failed to parse drum kit", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(kit)
+	case http.MethodPut:
+		var kit drums.KitFile
+		if err := json.NewDecoder(r.Body).Decode(&kit); err != nil {
+			http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+		if kit.Name == "" {
+			http.Error(w, "Drum kit name is required", http.StatusBadRequest)
+			return
+		}
+		if err := ws.drumsMapper.Check(&kit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.MarshalIndent(kit, "", "  ")
+		if err != nil {
+			http.Error(w, "Failed to serialize drum kit", http.StatusInternalServerError)
+			return
+		}
+		if err := os.WriteFile(filePath, append(data, '\n'), 0644); err != nil {
+			http.Error(w, "Failed to save drum kit", http.StatusInternalServerError)
+			return
+		}
+
+		// Reload the active kit so edits apply immediately.
+		if kitName == ws.drumsMapper.CurrentKit() {
+			if err := ws.drumsMapper.LoadKitFromFile(kitName); err != nil {
+				log.Printf("Warning: Failed to reload current drum kit after save: %v", err)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "saved"})
+	case http.MethodDelete:
+		if kitName == ws.drumsMapper.CurrentKit() {
+			http.Error(w, "Cannot delete the currently active drum kit", http.StatusBadRequest)
+			return
+		}
+		if err := os.Remove(filePath); err != nil {
+			if os.IsNotExist(err) {
+				http.Error(w, "Drum kit not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to delete drum kit", http.StatusInternalServerError)
+			}
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ws *WebServer) handleSwitchDrumKit(w http.ResponseWriter, r *http.Request) {
+	var request SwitchDrumKitRequest
+	if !decodePost(w, r, &request) {
+		return
+	}
+	if err := ws.drumsMapper.SwitchKit(request.File); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Switched to drum kit: %s\n", request.File)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "switched", "file": request.File})
+}
@@ -0,0 +1,97 @@
+package webserver
+
+import (
+	"ddp-sender/layout"
+	"ddp-sender/updater/mappings/custom"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	ZONE_SCOPE_GLOBAL  = "global"  // Defined in the layout configuration.
+	ZONE_SCOPE_MAPPING = "mapping" // Defined in the mapping file, shadows a global zone of the same name.
+)
+
+type LayoutResponse struct {
+	Points []layout.Point `json:"points"` // Position of every LED, indexed by canvas position.
+	Bounds layout.Rect    `json:"bounds"`
+}
+
+type ZoneInfo struct {
+	Name  string      `json:"name"`
+	Scope string      `json:"scope"`
+	Zone  layout.Zone `json:"zone"`
+	LEDs  []int       `json:"leds"`
+	Error string      `json:"error,omitempty"` // Set instead of LEDs when the zone cannot be resolved.
+}
+
+func (ws *WebServer) handleLayout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pixelLayout := ws.customMapper.Layout()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LayoutResponse{
+		Points: pixelLayout.Points(),
+		Bounds: pixelLayout.Bounds(),
+	})
+}
+
+// handleZones lists the global zones and, with ?mapping=name, the zones of that mapping file.
+func (ws *WebServer) handleZones(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var mappingFile custom.MappingFile
+	if mappingName := r.URL.Query().Get("mapping"); mappingName != "" {
+		if !strings.HasSuffix(mappingName, ".json") {
+			mappingName += ".json"
+		}
+		data, err := os.ReadFile(filepath.Join(ws.cfg.MappingsDir, filepath.Base(mappingName)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				http.Error(w, "Mapping not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to read mapping", http.StatusInternalServerError)
+			}
+			return
+		}
+		if err := json.Unmarshal(data, &mappingFile); err != nil {
+			http.Error(w, "Failed to parse mapping", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	pixelLayout := ws.customMapper.Layout()
+	zones := []ZoneInfo{}
+	add := func(name, scope string, zone layout.Zone) {
+		info := ZoneInfo{Name: name, Scope: scope, Zone: zone}
+		leds, err := pixelLayout.Zone(name, mappingFile.Zones)
+		if err != nil {
+			info.Error = err.Error()
+		} else {
+			info.LEDs = leds
+		}
+		zones = append(zones, info)
+	}
+	for name, zone := range pixelLayout.Zones() {
+		if _, shadowed := mappingFile.Zones[name]; !shadowed {
+			add(name, ZONE_SCOPE_GLOBAL, zone)
+		}
+	}
+	for name, zone := range mappingFile.Zones {
+		add(name, ZONE_SCOPE_MAPPING, zone)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Name < zones[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zones)
+}
@@ -0,0 +1,81 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type BrightnessRequest struct {
+	Brightness float64 `json:"brightness"` // 0-1
+}
+
+type BlackoutRequest struct {
+	On     bool `json:"on"`
+	FadeMs int  `json:"fadeMs,omitempty"` // 0 switches instantly.
+}
+
+type FreezeRequest struct {
+	On bool `json:"on"`
+}
+
+func (ws *WebServer) handleMaster(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ws.writeMasterState(w)
+}
+
+func (ws *WebServer) handleBrightness(w http.ResponseWriter, r *http.Request) {
+	var request BrightnessRequest
+	if !decodePost(w, r, &request) {
+		return
+	}
+	if request.Brightness < 0 || request.Brightness > 1 {
+		http.Error(w, "Brightness must be between 0 and 1", http.StatusBadRequest)
+		return
+	}
+	ws.ledArray.SetBrightness(request.Brightness)
+	ws.writeMasterState(w)
+}
+
+func (ws *WebServer) handleBlackout(w http.ResponseWriter, r *http.Request) {
+	var request BlackoutRequest
+	if !decodePost(w, r, &request) {
+		return
+	}
+	if request.FadeMs < 0 {
+		http.Error(w, "fadeMs must not be negative", http.StatusBadRequest)
+		return
+	}
+	ws.ledArray.SetBlackout(request.On, time.Duration(request.FadeMs)*time.Millisecond)
+	ws.writeMasterState(w)
+}
+
+func (ws *WebServer) handleFreeze(w http.ResponseWriter, r *http.Request) {
+	var request FreezeRequest
+	if !decodePost(w, r, &request) {
+		return
+	}
+	ws.ledArray.SetFreeze(request.On)
+	ws.writeMasterState(w)
+}
+
+func (ws *WebServer) writeMasterState(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ws.ledArray.MasterState())
+}
+
+// decodePost decodes the JSON body of a POST request, answering the error itself.
+func decodePost(w http.ResponseWriter, r *http.Request, request any) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
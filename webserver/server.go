@@ -1,8 +1,15 @@
 package webserver
 
 import (
+	"context"
 	"ddp-sender/config"
+	"ddp-sender/led"
+	"ddp-sender/output"
+	"ddp-sender/updater"
+	"ddp-sender/updater/effects"
 	"ddp-sender/updater/mappings/custom"
+	"ddp-sender/updater/mappings/drums"
+	"ddp-sender/util"
 	"embed"
 	"encoding/json"
 	"fmt"
@@ -13,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,12 +28,25 @@ import (
 var webUIFiles embed.FS
 
 type WebServer struct {
+	cfg          *config.Config
+	ledArray     *led.LEDArrayColor
+	outputs      *output.Manager
 	customMapper *custom.CustomMapper
+	drumsMapper  *drums.DrumsMapper
+	updater      *updater.Updater
+
+	testPattern  *effects.Pattern
+	patternMutex sync.Mutex
 }
 
-func NewWebServer(customMapper *custom.CustomMapper) *WebServer {
+func NewWebServer(cfg *config.Config, ledArray *led.LEDArrayColor, outputs *output.Manager, u *updater.Updater) *WebServer {
 	return &WebServer{
-		customMapper: customMapper,
+		cfg:          cfg,
+		ledArray:     ledArray,
+		outputs:      outputs,
+		customMapper: u.GetCustomMapper(),
+		drumsMapper:  u.GetDrumsMapper(),
+		updater:      u,
 	}
 }
 
@@ -37,17 +58,30 @@ func (ws *WebServer) setupRoutes() *http.ServeMux {
 	mux.HandleFunc("/api/switchMapping", ws.customMapper.SwitchMappingHandler)
 	mux.HandleFunc("/api/mappings", ws.handleMappings)
 	mux.HandleFunc("/api/mappings/", ws.handleMappingOperations)
+	mux.HandleFunc("/api/activeMappings", ws.handleActiveMappings)
+	mux.HandleFunc("/api/activeMappings/", ws.handleDeactivateMapping)
+	mux.HandleFunc("/api/drums", ws.handleDrumKits)
+	mux.HandleFunc("/api/drums/", ws.handleDrumKitOperations)
+	mux.HandleFunc("/api/switchDrumKit", ws.handleSwitchDrumKit)
 	mux.HandleFunc("/api/trigger", ws.handleTriggerPreset)
 	mux.HandleFunc("/api/trigger/clear", ws.handleTriggerPreset)
 	mux.HandleFunc("/api/preview-effect", ws.handlePreviewEffect)
 	mux.HandleFunc("/api/preview-effect/clear", ws.handleClearPreview)
+	mux.HandleFunc("/api/calibration", ws.handleCalibration)
+	mux.HandleFunc("/api/calibration/test-pattern", ws.handleTestPattern)
+	mux.HandleFunc("/api/layout", ws.handleLayout)
+	mux.HandleFunc("/api/zones", ws.handleZones)
+	mux.HandleFunc("/api/master", ws.handleMaster)
+	mux.HandleFunc("/api/master/brightness", ws.handleBrightness)
+	mux.HandleFunc("/api/master/blackout", ws.handleBlackout)
+	mux.HandleFunc("/api/master/freeze", ws.handleFreeze)
 
 	// Static file serving for React app
 	webUIFS, err := fs.Sub(webUIFiles, "ui/dist")
 	if err != nil {
 		log.Printf("Warning: Could not setup embedded web UI files: %v", err)
 		// Fallback to file system serving for development
-		mux.Handle("/", http.FileServer(http.Dir(config.WEB_UI_DIR)))
+		mux.Handle("/", http.FileServer(http.Dir(ws.cfg.WebUIDir)))
 	} else {
 		// Serve static files
 		mux.Handle("/static/", http.FileServer(http.FS(webUIFS)))
@@ -92,22 +126,35 @@ func (ws *WebServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	status := fmt.Sprintf(`{
-		"currentMapping": "%s",
-		"ledCount": %d,
-		"status": "running"
-	}`, config.CURRENT_MAPPING, config.LED_AMOUNT)
+	json.NewEncoder(w).Encode(StatusResponse{
+		CurrentMapping: ws.customMapper.CurrentMapping(),
+		ActiveMappings: ws.updater.ActiveMappings(),
+		CurrentDrumKit: ws.drumsMapper.CurrentKit(),
+		LEDCount:       ws.cfg.LEDAmount,
+		Status:         "running",
+		Outputs:        ws.outputs.Stats(),
+		Master:         ws.ledArray.MasterState(),
+	})
+}
 
-	w.Write([]byte(status))
+type StatusResponse struct {
+	CurrentMapping string                  `json:"currentMapping"`
+	ActiveMappings []updater.ActiveMapping `json:"activeMappings"` // Routed and activated next to the current mapping.
+	CurrentDrumKit string                  `json:"currentDrumKit"`
+	LEDCount       int                     `json:"ledCount"`
+	Status         string                  `json:"status"`
+	Outputs        []output.Stats          `json:"outputs"` // Includes the power estimate and scale of every output.
+	Master         led.MasterState         `json:"master"`
 }
 
 type MappingListItem struct {
-	Name         string `json:"name"`
-	Title        string `json:"title"`
-	Description  string `json:"description"`
-	PresetCount  int    `json:"presetCount"`
-	LastModified string `json:"lastModified"`
-	IsActive     bool   `json:"isActive"`
+	Name         string                 `json:"name"`
+	Title        string                 `json:"title"`
+	Description  string                 `json:"description"`
+	PresetCount  int                    `json:"presetCount"`
+	LastModified string                 `json:"lastModified"`
+	IsActive     bool                   `json:"isActive"`          // Current mapping, switched by switchMapping.
+	Binding      *updater.ActiveMapping `json:"binding,omitempty"` // Set when routed or activated next to the current mapping.
 }
 
 func (ws *WebServer) handleMappings(w http.ResponseWriter, r *http.Request) {
@@ -122,13 +169,17 @@ func (ws *WebServer) handleMappings(w http.ResponseWriter, r *http.Request) {
 }
 
 func (ws *WebServer) handleGetMappings(w http.ResponseWriter, _ *http.Request) {
-	files, err := os.ReadDir(config.MAPPINGS_DIR)
+	files, err := os.ReadDir(ws.cfg.MappingsDir)
 	if err != nil {
 		http.Error(w, "Failed to read mappings directory", http.StatusInternalServerError)
 		return
 	}
 
 	var mappings []MappingListItem
+	bindings := make(map[string]updater.ActiveMapping)
+	for _, binding := range ws.updater.ActiveMappings() {
+		bindings[binding.File] = binding
+	}
 
 	for _, file := range files {
 		if !strings.HasSuffix(file.Name(), ".json") {
@@ -136,7 +187,7 @@ func (ws *WebServer) handleGetMappings(w http.ResponseWriter, _ *http.Request) {
 		}
 
 		// Read file to get metadata
-		filePath := filepath.Join(config.MAPPINGS_DIR, file.Name())
+		filePath := filepath.Join(ws.cfg.MappingsDir, file.Name())
 		data, err := os.ReadFile(filePath)
 		if err != nil {
 			log.Printf("Error reading mapping file %s: %v", file.Name(), err)
@@ -156,14 +207,18 @@ func (ws *WebServer) handleGetMappings(w http.ResponseWriter, _ *http.Request) {
 			continue
 		}
 
-		mappings = append(mappings, MappingListItem{
+		item := MappingListItem{
 			Name:         file.Name(),
 			Title:        mappingFile.Name,
 			Description:  mappingFile.Description,
 			PresetCount:  len(mappingFile.Presets),
 			LastModified: info.ModTime().Format("2006-01-02"),
-			IsActive:     file.Name() == config.CURRENT_MAPPING,
-		})
+			IsActive:     file.Name() == ws.customMapper.CurrentMapping(),
+		}
+		if binding, ok := bindings[file.Name()]; ok {
+			item.Binding = &binding
+		}
+		mappings = append(mappings, item)
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -198,7 +253,7 @@ func (ws *WebServer) handleMappingOperations(w http.ResponseWriter, r *http.Requ
 }
 
 func (ws *WebServer) handleGetMapping(w http.ResponseWriter, r *http.Request, mappingName string) {
-	filePath := filepath.Join(config.MAPPINGS_DIR, mappingName)
+	filePath := filepath.Join(ws.cfg.MappingsDir, mappingName)
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -233,9 +288,18 @@ func (ws *WebServer) handleSaveMapping(w http.ResponseWriter, r *http.Request, m
 		http.Error(w, "Mapping name is required", http.StatusBadRequest)
 		return
 	}
+	if err := mappingFile.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Reject zones referencing unknown zones and presets targeting them.
+	if _, err := ws.customMapper.Resolve(&mappingFile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Save to file
-	filePath := filepath.Join(config.MAPPINGS_DIR, mappingName)
+	filePath := filepath.Join(ws.cfg.MappingsDir, mappingName)
 	data, err := json.MarshalIndent(mappingFile, "", "  ")
 	if err != nil {
 		http.Error(w, "Failed to serialize mapping", http.StatusInternalServerError)
@@ -250,12 +314,15 @@ func (ws *WebServer) handleSaveMapping(w http.ResponseWriter, r *http.Request, m
 	}
 
 	// If this is the current mapping, reload it
-	if mappingName == config.CURRENT_MAPPING {
+	if mappingName == ws.customMapper.CurrentMapping() {
 		err = ws.customMapper.LoadMappingFromFile(mappingName)
 		if err != nil {
 			log.Printf("Warning: Failed to reload current mapping after save: %v", err)
 		}
 	}
+	if err := ws.updater.ReloadMapping(mappingName); err != nil {
+		log.Printf("Warning: Failed to reload active mapping after save: %v", err)
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "saved"})
@@ -263,12 +330,18 @@ func (ws *WebServer) handleSaveMapping(w http.ResponseWriter, r *http.Request, m
 
 func (ws *WebServer) handleDeleteMapping(w http.ResponseWriter, r *http.Request, mappingName string) {
 	// Prevent deleting the current mapping
-	if mappingName == config.CURRENT_MAPPING {
+	if mappingName == ws.customMapper.CurrentMapping() {
 		http.Error(w, "Cannot delete the currently active mapping", http.StatusBadRequest)
 		return
 	}
+	for _, binding := range ws.updater.ActiveMappings() {
+		if binding.File == mappingName {
+			http.Error(w, "Cannot delete an active mapping", http.StatusBadRequest)
+			return
+		}
+	}
 
-	filePath := filepath.Join(config.MAPPINGS_DIR, mappingName)
+	filePath := filepath.Join(ws.cfg.MappingsDir, mappingName)
 	err := os.Remove(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -291,6 +364,7 @@ type PreviewEffectRequest struct {
 	Effect  string          `json:"effect"`
 	Options json.RawMessage `json:"options"`
 	On      *bool           `json:"on,omitempty"`
+	effects.LayerOptions
 }
 
 type TriggerRequest struct {
@@ -391,7 +465,7 @@ func (ws *WebServer) handlePreviewEffect(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Validate range
-	maxLED := config.LED_AMOUNT - 1
+	maxLED := ws.cfg.LEDAmount - 1
 	if request.First < 0 || request.First > maxLED || request.Last < 0 || request.Last > maxLED {
 		http.Error(w, fmt.Sprintf("LED range must be between 0 and %d", maxLED), http.StatusBadRequest)
 		return
@@ -405,7 +479,7 @@ func (ws *WebServer) handlePreviewEffect(w http.ResponseWriter, r *http.Request)
 
 	// Trigger the preview effect through the custom mapper
 	if isOn {
-		err = ws.customMapper.TriggerPreviewEffect(request.First, request.Last, request.Step, request.Color, request.Effect, request.Options)
+		err = ws.customMapper.TriggerPreviewEffect(request.First, request.Last, request.Step, request.Color, request.Effect, request.Options, request.LayerOptions)
 	} else {
 		err = ws.customMapper.TriggerPreviewEffectOff()
 	}
@@ -448,11 +522,12 @@ func (ws *WebServer) handleClearPreview(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(map[string]string{"status": "preview cleared"})
 }
 
-func (ws *WebServer) Start() error {
+// Start serves the web UI and API until ctx is cancelled.
+func (ws *WebServer) Start(ctx context.Context) error {
 	mux := ws.setupRoutes()
 
-	addr := fmt.Sprintf(":%d", config.WEB_UI_PORT)
+	addr := fmt.Sprintf(":%d", ws.cfg.WebUIPort)
 	log.Printf("Starting web server on http://localhost%s", addr)
 
-	return http.ListenAndServe(addr, mux)
+	return util.ServeHTTP(ctx, &http.Server{Addr: addr, Handler: mux})
 }
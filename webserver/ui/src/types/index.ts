@@ -3,6 +3,9 @@
 // Core Effect Types
 export type EffectType = "static" | "decay" | "sweep" | "syncWalk";
 
+// Layer blend modes (see effects.BlendMode)
+export type BlendMode = "replace" | "add" | "max" | "multiply" | "alpha";
+
 // Effect Options
 export interface DecayOptions {
   decay_coef: number;
@@ -29,6 +32,14 @@ export type EffectOptions =
   | SyncWalkOptions
   | StaticOptions;
 
+// Layout rectangle, selects X <= x < X+width and Y <= y < Y+height
+export interface LayoutRect {
+  x: number;
+  y: number;
+  width: number;
+  height: number;
+}
+
 // Preset Definition
 export interface Preset {
   id?: number;
@@ -37,15 +48,34 @@ export interface Preset {
   first: number;
   last: number;
   step: number;
+  zone?: string; // Mapping or global zone, replaces first/last/step
+  rect?: LayoutRect; // Layout rectangle, replaces first/last/step
   color: string;
   effect: EffectType;
   options: EffectOptions;
+  layer?: number;
+  blend?: BlendMode;
+  opacity?: number;
+}
+
+// Zone: union of ranges, layout rectangles and other zones
+export interface ZoneRange {
+  first: number;
+  last: number;
+  step?: number; // Defaults to 1
+}
+
+export interface Zone {
+  ranges?: ZoneRange[];
+  rects?: LayoutRect[];
+  zones?: string[];
 }
 
 // Mapping File Structure
 export interface MappingFile {
   name: string;
   description?: string;
+  zones?: Record<string, Zone>; // Shadow the global zones
   presets: Preset[];
 }
 
@@ -66,10 +96,59 @@ export interface LEDMapping {
 }
 
 // API Response Types
+export interface PowerEstimate {
+  milliamps: number;
+  limitedMilliamps: number;
+  scale: number;
+}
+
+export interface OutputStats {
+  name: string;
+  sent: number;
+  errors: number;
+  dropped: number;
+  lastError?: string;
+  lastLatency: number; // Nanoseconds
+  maxLatency: number; // Nanoseconds
+  power: PowerEstimate;
+  nodes?: ArtNetNode[]; // Art-Net nodes discovered at the destination
+}
+
+export interface ArtNetNode {
+  ip: string;
+  shortName: string;
+  longName: string;
+  lastSeen: string;
+}
+
+export interface MasterState {
+  brightness: number;
+  blackout: boolean;
+  level: number; // Blackout fade level, 0 is black
+  frozen: boolean;
+}
+
+export interface BlackoutRequest {
+  on: boolean;
+  fadeMs?: number;
+}
+
+// Mapping active next to the current one, routed by the configuration or activated
+export interface ActiveMapping {
+  file: string;
+  channels: number[]; // Empty when following the routes of the current mapping
+  layer?: number; // Layer of all its effects instead of the preset layers
+  routed: boolean; // Bound by the routes of the configuration, cannot be deactivated
+}
+
 export interface SystemStatus {
   currentMapping: string;
+  activeMappings: ActiveMapping[];
+  currentDrumKit: string;
   ledCount: number;
   status: "running" | "stopped" | "error";
+  outputs: OutputStats[];
+  master: MasterState;
 }
 
 export interface MappingListItem {
@@ -79,11 +158,88 @@ export interface MappingListItem {
   presetCount: number;
   lastModified: string;
   isActive: boolean;
+  binding?: ActiveMapping;
+}
+
+export interface DrumKitListItem {
+  name: string;
+  title: string;
+  description?: string;
+  groupCount: number;
+  lastModified: string;
+  isActive: boolean;
+}
+
+export type DrumGroupMode = "hold" | "trigger";
+
+// Drum kit group: hold LEDs until note off or trigger an effect on note on
+export interface DrumGroup {
+  name: string;
+  notes: number[];
+  threshold?: number; // Note on velocities must exceed it
+  mode: DrumGroupMode;
+  leds: Zone;
+  color: string;
+  velocity?: boolean; // Scale the color by velocity/127
+  effect?: EffectType; // Trigger mode
+  options?: EffectOptions;
+  layer?: number;
+  blend?: BlendMode;
+  opacity?: number;
+}
+
+// Drum Kit File Structure
+export interface DrumKitFile {
+  name: string;
+  description?: string;
+  groups: DrumGroup[];
+  default?: Omit<DrumGroup, "notes" | "leds">;
 }
 
 // API Request Types
+export interface SwitchDrumKitRequest {
+  file: string;
+}
+
+export type MappingTransition = "cut" | "finish" | "crossfade" | "black";
+
+// Transition and fadeMs override the configured transition, fadeMs 0 included
 export interface SwitchMappingRequest {
   file: string;
+  transition?: MappingTransition;
+  fadeMs?: number;
+}
+
+// Layout (see /api/layout)
+export interface LayoutPoint {
+  x: number;
+  y: number;
+}
+
+export interface LayoutInfo {
+  points: LayoutPoint[]; // Indexed by LED
+  bounds: LayoutRect;
+}
+
+// Zones (see /api/zones)
+export interface ZoneInfo {
+  name: string;
+  scope: "global" | "mapping";
+  zone: Zone;
+  leds?: number[];
+  error?: string; // Set when the zone cannot be resolved
+}
+
+// Calibration (see /api/calibration)
+export type TestPattern = "gray-ramp" | "primaries" | "off";
+
+export interface CalibrationInfo {
+  profiles: string[];
+  outputs: Record<string, string>;
+}
+
+export interface TestPatternRequest {
+  pattern: TestPattern;
 }
 
 // API Response Types for Go backend
@@ -168,7 +324,7 @@ export interface MIDIConfig {
 export interface LEDConfig {
   count: number;
   indexBase: 1; // Always 1-based indexing
-  layout: "linear"; // Future: 'matrix' | 'custom'
+  layout: "linear"; // 2D positions come from /api/layout
 }
 
 // System Configuration
@@ -209,6 +365,14 @@ export type PartialPreset = Partial<Preset>;
 export type RequiredPreset = Required<Preset>;
 
 // Constants
+export const BLEND_MODES: readonly BlendMode[] = [
+  "replace",
+  "add",
+  "max",
+  "multiply",
+  "alpha",
+] as const;
+
 export const EFFECT_TYPES: readonly EffectType[] = [
   "static",
   "decay",